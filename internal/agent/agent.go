@@ -1,35 +1,108 @@
 package agent
 
-import "time"
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
-// AgentContext provides context to a running agent.
+// AgentContext provides context to a running agent. It is also the payload
+// sent to plugin agents, hence the JSON tags.
 type AgentContext struct {
-	RepoPath string
-	Branch   string
-	Diff     string
-	Files    []string
-	Env      map[string]string
+	RepoPath string            `json:"repo_path"`
+	Branch   string            `json:"branch"`
+	Diff     string            `json:"diff"`
+	Files    []string          `json:"files"`
+	Env      map[string]string `json:"env,omitempty"`
+	// BaseRef is the revision the changes are compared against. For staged
+	// changes this is the HEAD commit.
+	BaseRef string `json:"base_ref,omitempty"`
+	// HeadRef is the revision holding the changes. Empty means the working tree.
+	HeadRef string `json:"head_ref,omitempty"`
+	// CommitMessage is the message being committed, as passed to the
+	// commit-msg hook.
+	CommitMessage string `json:"commit_message,omitempty"`
+	// Commits lists the commits between BaseRef and HeadRef in range mode.
+	Commits []Commit `json:"commits,omitempty"`
+	// Suggest asks agents that can draft content, such as a commit message
+	// or a CHANGELOG entry, to do so (verifier run --suggest).
+	Suggest bool `json:"suggest,omitempty"`
+	// Fix asks agents that can fix what they report to do so in place
+	// (verifier run --fix).
+	Fix bool `json:"fix,omitempty"`
+	// NewCode is the agent's agents.<id>.new_code mode. The runner hides the
+	// findings outside the change after the agent ran.
+	NewCode string `json:"new_code,omitempty"`
 }
 
-// AgentArtifact represents a file or content generated by an agent.
+// Commit identifies a commit and its message.
+type Commit struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+// Finding is a single issue reported by an agent at a location in the repo.
+// Lines and columns are 1-based; EndLine and EndColumn are inclusive.
+type Finding struct {
+	// Rule identifies the check that produced the finding, e.g.
+	// "lint/ruff/F401" or "security/sql-injection".
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"` // "info", "warning", "blocking"
+	Message   string `json:"message"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	EndColumn int    `json:"end_column,omitempty"`
+	// Fingerprint identifies the finding across runs. It ignores the line, so
+	// it survives unrelated edits above the finding. CreateResult fills it in
+	// when the agent leaves it empty.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Confidence is the reporter's certainty from 0 to 1, when known.
+	Confidence float64 `json:"confidence,omitempty"`
+	// CWE lists weakness identifiers such as "CWE-89".
+	CWE  []string `json:"cwe,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// Suggestion is replacement text for the lines Line through EndLine.
+	Suggestion string `json:"suggestion,omitempty"`
+	// Remediation describes how to fix the issue when no exact replacement
+	// is known.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// fingerprint hashes the rule, file and message of f.
+func (f Finding) fingerprint() string {
+	sum := sha256.Sum256([]byte(f.Rule + "\x00" + f.File + "\x00" + f.Message))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AgentArtifact represents a file or content generated by an agent. Patch
+// artifacts carry a unified diff in Content and name the rule of the finding
+// they fix, so the fix can be confirmed by re-running the agent.
 type AgentArtifact struct {
-	Type    string `json:"type"`
+	Type    string `json:"type"` // "report", "patch", ...
 	Path    string `json:"path,omitempty"`
 	Content string `json:"content,omitempty"`
+	Rule    string `json:"rule,omitempty"`
 }
 
 // AgentResult is the output from an agent execution.
 type AgentResult struct {
-	AgentID     string          `json:"agent_id"`
-	Status      string          `json:"status"` // "success", "failure", "skipped"
-	Error       string          `json:"error,omitempty"`
-	Data        any             `json:"data,omitempty"`
-	Severity    string          `json:"severity,omitempty"` // "info", "warning", "blocking"
-	TokensUsed  int             `json:"tokens_used,omitempty"`
-	Cost        float64         `json:"cost,omitempty"`
-	Score       int             `json:"score,omitempty"`
-	Artifacts   []AgentArtifact `json:"artifacts,omitempty"`
-	Timestamp   string          `json:"timestamp"`
+	AgentID  string    `json:"agent_id"`
+	Status   string    `json:"status"` // "success", "failure", "skipped"
+	Error    string    `json:"error,omitempty"`
+	Data     any       `json:"data,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+	// HiddenFindings counts the findings outside the change, see
+	// config.AgentSettings.NewCode.
+	HiddenFindings int             `json:"hidden_findings,omitempty"`
+	Severity       string          `json:"severity,omitempty"` // "info", "warning", "blocking"
+	TokensUsed     int             `json:"tokens_used,omitempty"`
+	Cost           float64         `json:"cost,omitempty"`
+	Score          int             `json:"score,omitempty"`
+	Artifacts      []AgentArtifact `json:"artifacts,omitempty"`
+	Timestamp      string          `json:"timestamp"`
 }
 
 // BaseAgent provides a common structure for agents.
@@ -56,6 +129,11 @@ func (b *BaseAgent) CreateResult(partial AgentResult) AgentResult {
 	if partial.Status == "" {
 		partial.Status = "success"
 	}
+	for i := range partial.Findings {
+		if partial.Findings[i].Fingerprint == "" {
+			partial.Findings[i].Fingerprint = partial.Findings[i].fingerprint()
+		}
+	}
 	partial.Timestamp = time.Now().UTC().Format(time.RFC3339)
 	return partial
 }
@@ -67,3 +145,11 @@ type Agent interface {
 	Model() string
 	Execute(ctx AgentContext) (*AgentResult, error)
 }
+
+// ContextAgent is implemented by agents that run subprocesses, check out
+// worktrees or edit the working tree. ExecuteContext stops early when runCtx
+// is cancelled, but only returns once its worktrees are removed and the
+// working tree is restored.
+type ContextAgent interface {
+	ExecuteContext(runCtx context.Context, ctx AgentContext) (*AgentResult, error)
+}
@@ -0,0 +1,387 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"golang.org/x/tools/go/packages"
+)
+
+type APICompatAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewAPICompatAgent(cfg *config.Config) Agent {
+	return &APICompatAgent{
+		BaseAgent: BaseAgent{id: "api-compat", description: "Detects breaking changes to exported Go APIs", model: "none"},
+		cfg:       cfg,
+	}
+}
+
+func (*APICompatAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"Go"}}
+}
+
+// APIChange is a single difference in the exported API of a package.
+type APIChange struct {
+	Package string `json:"package"`
+	Symbol  string `json:"symbol"`
+	Change  string `json:"change"` // "removed", "changed", "added"
+	Impact  string `json:"impact"` // "major", "minor"
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+type APICompatReport struct {
+	BaseModule      string      `json:"base_module"`
+	HeadModule      string      `json:"head_module"`
+	MajorBumped     bool        `json:"major_bumped"`
+	RecommendedBump string      `json:"recommended_bump"` // "major", "minor", "none"
+	Changes         []APIChange `json:"changes"`
+	Findings        []Finding   `json:"-"`
+}
+
+// apiSymbol describes one exported symbol: its kind, a normalised signature
+// and where it is declared.
+type apiSymbol struct {
+	kind string
+	desc string
+	file string
+	line int
+}
+
+func (a *APICompatAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	return a.ExecuteContext(context.Background(), ctx)
+}
+
+func (a *APICompatAgent) ExecuteContext(runCtx context.Context, ctx AgentContext) (*AgentResult, error) {
+	if ctx.BaseRef == "" {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No base revision to compare against"})
+		return &res, nil
+	}
+
+	files, err := patch.Parse(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+	dirSet := make(map[string]bool)
+	for _, f := range files {
+		for _, p := range []string{f.OldPath, f.NewPath} {
+			if strings.HasSuffix(p, ".go") && !strings.HasSuffix(p, "_test.go") {
+				dirSet[filepath.ToSlash(filepath.Dir(p))] = true
+			}
+		}
+	}
+	if len(dirSet) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No Go source files changed"})
+		return &res, nil
+	}
+	var dirs []string
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	baseDir, cleanupBase, err := checkoutRevision(runCtx, repoPath, ctx.BaseRef)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupBase()
+	headDir, cleanupHead, err := checkoutRevision(runCtx, repoPath, ctx.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupHead()
+
+	baseAPI, err := loadExportedAPI(runCtx, baseDir, dirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to type-check base: %w", err)
+	}
+	headAPI, err := loadExportedAPI(runCtx, headDir, dirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to type-check head: %w", err)
+	}
+
+	report := APICompatReport{
+		BaseModule: goModulePath(baseDir),
+		HeadModule: goModulePath(headDir),
+	}
+	report.MajorBumped = moduleMajor(report.BaseModule) != moduleMajor(report.HeadModule)
+	report.Changes, report.Findings = diffAPI(baseAPI, headAPI, report.MajorBumped)
+
+	report.RecommendedBump = "none"
+	hasBlocking := false
+	for _, c := range report.Changes {
+		if c.Impact == "major" {
+			report.RecommendedBump = "major"
+		} else if report.RecommendedBump == "none" {
+			report.RecommendedBump = "minor"
+		}
+	}
+	for _, f := range report.Findings {
+		if f.Severity == "blocking" {
+			hasBlocking = true
+		}
+	}
+
+	severity := "info"
+	if hasBlocking {
+		severity = "blocking"
+	} else if report.RecommendedBump == "major" {
+		severity = "warning"
+	}
+
+	res := a.CreateResult(AgentResult{Data: report, Findings: report.Findings, Severity: severity})
+	return &res, nil
+}
+
+// loadExportedAPI type-checks the given package directories under root and
+// returns the exported symbols of each importable package, keyed by package
+// path relative to the module.
+func loadExportedAPI(runCtx context.Context, root string, dirs []string) (map[string]map[string]apiSymbol, error) {
+	var patterns []string
+	for _, dir := range dirs {
+		if info, err := os.Stat(filepath.Join(root, dir)); err == nil && info.IsDir() {
+			patterns = append(patterns, "./"+dir)
+		}
+	}
+	api := make(map[string]map[string]apiSymbol)
+	if len(patterns) == 0 {
+		return api, nil
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := packages.Load(&packages.Config{
+		Context: runCtx,
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedModule,
+		Dir:     root,
+		Fset:    fset,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.Name == "main" || isInternalPackage(pkg.PkgPath) {
+			continue
+		}
+		rel := pkg.PkgPath
+		if pkg.Module != nil {
+			rel = relativePackage(pkg.PkgPath, pkg.Module.Path)
+		}
+		api[rel] = exportedSymbols(pkg.Types, fset, root)
+	}
+	return api, nil
+}
+
+func exportedSymbols(pkg *types.Package, fset *token.FileSet, root string) map[string]apiSymbol {
+	symbols := make(map[string]apiSymbol)
+	qualifier := types.RelativeTo(pkg)
+	add := func(key, kind, desc string, pos token.Pos) {
+		s := apiSymbol{kind: kind, desc: desc}
+		if p := fset.Position(pos); p.IsValid() {
+			if rel, err := filepath.Rel(root, p.Filename); err == nil {
+				s.file = filepath.ToSlash(rel)
+			}
+			s.line = p.Line
+		}
+		symbols[key] = s
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch o := obj.(type) {
+		case *types.Func:
+			add("func "+name, "func", signatureString(o.Type(), qualifier), o.Pos())
+		case *types.Var:
+			add("var "+name, "var", types.TypeString(o.Type(), qualifier), o.Pos())
+		case *types.Const:
+			add("const "+name, "const", types.TypeString(o.Type(), qualifier), o.Pos())
+		case *types.TypeName:
+			if o.IsAlias() {
+				add("type "+name, "type", "= "+types.TypeString(o.Type(), qualifier), o.Pos())
+				continue
+			}
+			named, ok := o.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			switch u := named.Underlying().(type) {
+			case *types.Struct:
+				add("type "+name, "type", "struct", o.Pos())
+				for i := 0; i < u.NumFields(); i++ {
+					if f := u.Field(i); f.Exported() {
+						add("field "+name+"."+f.Name(), "field", types.TypeString(f.Type(), qualifier), f.Pos())
+					}
+				}
+			case *types.Interface:
+				add("type "+name, "type", "interface", o.Pos())
+				for i := 0; i < u.NumMethods(); i++ {
+					if m := u.Method(i); m.Exported() {
+						add("method "+name+"."+m.Name(), "interface-method", signatureString(m.Type(), qualifier), m.Pos())
+					}
+				}
+				continue
+			default:
+				add("type "+name, "type", types.TypeString(u, qualifier), o.Pos())
+			}
+			mset := types.NewMethodSet(types.NewPointer(named))
+			for i := 0; i < mset.Len(); i++ {
+				if m := mset.At(i).Obj(); m.Exported() {
+					add("method "+name+"."+m.Name(), "method", signatureString(m.Type(), qualifier), m.Pos())
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// signatureString formats a function type without parameter names, so that
+// renaming a parameter is not reported as a change.
+func signatureString(t types.Type, qualifier types.Qualifier) string {
+	sig, ok := t.(*types.Signature)
+	if !ok || sig.TypeParams().Len() > 0 {
+		// Type parameters cannot be rebound to a new signature.
+		return types.TypeString(t, qualifier)
+	}
+	unnamed := func(tuple *types.Tuple) *types.Tuple {
+		vars := make([]*types.Var, tuple.Len())
+		for i := range vars {
+			vars[i] = types.NewParam(token.NoPos, nil, "", tuple.At(i).Type())
+		}
+		return types.NewTuple(vars...)
+	}
+	stripped := types.NewSignatureType(nil, nil, nil, unnamed(sig.Params()), unnamed(sig.Results()), sig.Variadic())
+	return types.TypeString(stripped, qualifier)
+}
+
+// diffAPI compares two API snapshots. Removing or changing a symbol, or adding
+// a method to an existing interface, breaks callers and is a major change.
+func diffAPI(base, head map[string]map[string]apiSymbol, majorBumped bool) ([]APIChange, []Finding) {
+	var changes []APIChange
+	var findings []Finding
+
+	record := func(pkg, key, change, impact string, before, after apiSymbol) {
+		c := APIChange{Package: pkg, Symbol: key, Change: change, Impact: impact, Before: before.desc, After: after.desc}
+		changes = append(changes, c)
+
+		loc := after
+		if change == "removed" {
+			loc = before
+		}
+		severity := "info"
+		if impact == "major" {
+			severity = "warning"
+			if !majorBumped {
+				severity = "blocking"
+			}
+		}
+		msg := fmt.Sprintf("%s %s in package %s (%s change)", key, change, pkg, impact)
+		if change == "changed" {
+			msg = fmt.Sprintf("%s in package %s changed from %q to %q (%s change)", key, pkg, before.desc, after.desc, impact)
+		}
+		findings = append(findings, Finding{
+			Rule:     "api-" + change,
+			Severity: severity,
+			Message:  msg,
+			File:     loc.file,
+			Line:     loc.line,
+		})
+	}
+
+	for _, pkg := range sortedKeys(base, head) {
+		b, h := base[pkg], head[pkg]
+		keys := make(map[string]bool)
+		for k := range b {
+			keys[k] = true
+		}
+		for k := range h {
+			keys[k] = true
+		}
+		var sorted []string
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			before, inBase := b[key]
+			after, inHead := h[key]
+			switch {
+			case inBase && !inHead:
+				record(pkg, key, "removed", "major", before, after)
+			case !inBase && inHead:
+				isMember := after.kind == "field" || after.kind == "method" || after.kind == "interface-method"
+				if isMember && !typeExisted(b, key) {
+					continue // members of a new type are covered by the type itself
+				}
+				impact := "minor"
+				if after.kind == "interface-method" {
+					impact = "major"
+				}
+				record(pkg, key, "added", impact, before, after)
+			case before.desc != after.desc || before.kind != after.kind:
+				record(pkg, key, "changed", "major", before, after)
+			}
+		}
+	}
+	return changes, findings
+}
+
+// typeExisted reports whether the type owning a member key such as
+// "method T.M" was present in the snapshot.
+func typeExisted(symbols map[string]apiSymbol, memberKey string) bool {
+	_, member, _ := strings.Cut(memberKey, " ")
+	typeName, _, _ := strings.Cut(member, ".")
+	_, ok := symbols["type "+typeName]
+	return ok
+}
+
+func sortedKeys(maps ...map[string]map[string]apiSymbol) []string {
+	set := make(map[string]bool)
+	for _, m := range maps {
+		for k := range m {
+			set[k] = true
+		}
+	}
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var majorSuffix = regexp.MustCompile(`/v([0-9]+)$`)
+
+// moduleMajor returns the major version implied by a module path; paths
+// without a /vN suffix are v0 or v1.
+func moduleMajor(modulePath string) string {
+	if m := majorSuffix.FindStringSubmatch(modulePath); m != nil {
+		return "v" + m[1]
+	}
+	return "v1"
+}
+
+func isInternalPackage(pkgPath string) bool {
+	return strings.HasPrefix(pkgPath, "internal/") || strings.Contains(pkgPath, "/internal/") || strings.HasSuffix(pkgPath, "/internal")
+}
@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+)
+
+type BenchAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewBenchAgent(cfg *config.Config) Agent {
+	return &BenchAgent{
+		BaseAgent: BaseAgent{id: "bench", description: "Compares Go benchmarks of changed packages between the base and head revisions", model: "none"},
+		cfg:       cfg,
+	}
+}
+
+func (*BenchAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"Go"}}
+}
+
+// BenchOptions configures the bench agent under agents.bench.options.
+type BenchOptions struct {
+	// Count is the number of runs of each benchmark per revision.
+	Count int `mapstructure:"count" yaml:"count"`
+	// Benchtime is passed to go test -benchtime when set.
+	Benchtime string `mapstructure:"benchtime" yaml:"benchtime"`
+	// WarningPct and BlockingPct are the slowdowns, in percent of the base
+	// median, at which a significant regression warns or blocks.
+	WarningPct  float64 `mapstructure:"warning_pct" yaml:"warning_pct"`
+	BlockingPct float64 `mapstructure:"blocking_pct" yaml:"blocking_pct"`
+}
+
+func (o BenchOptions) Validate() error {
+	if o.Count < 2 {
+		return errors.New("count must be at least 2 for a significance test")
+	}
+	if o.WarningPct <= 0 || o.BlockingPct < o.WarningPct {
+		return errors.New("warning_pct must be positive and at most blocking_pct")
+	}
+	return nil
+}
+
+func (*BenchAgent) DefaultOptions() any {
+	return BenchOptions{Count: 6, WarningPct: 5, BlockingPct: 20}
+}
+
+// BenchMetric compares one unit of a benchmark. Base and the comparison
+// fields are nil when the benchmark did not run on the base revision.
+type BenchMetric struct {
+	Unit        string        `json:"unit"` // "ns/op", "B/op", "allocs/op"
+	Base        *benchSummary `json:"base,omitempty"`
+	Head        benchSummary  `json:"head"`
+	DeltaPct    *float64      `json:"delta_pct,omitempty"`
+	PValue      *float64      `json:"p_value,omitempty"`
+	Significant bool          `json:"significant"`
+}
+
+type BenchComparison struct {
+	Package string        `json:"package"`
+	Name    string        `json:"name"`
+	File    string        `json:"file"`
+	Line    int           `json:"line"`
+	Metrics []BenchMetric `json:"metrics"`
+}
+
+type BenchReport struct {
+	Packages   []string          `json:"packages"`
+	Benchmarks []BenchComparison `json:"benchmarks"`
+	BaseError  string            `json:"base_error,omitempty"`
+	Findings   []Finding         `json:"-"`
+}
+
+// benchUnits are the metrics reported by go test -benchmem. Regressions are
+// only reported for the units in benchRegressionUnits; B/op is informational.
+var (
+	benchUnits           = []string{"ns/op", "B/op", "allocs/op"}
+	benchRegressionUnits = map[string]bool{"ns/op": true, "allocs/op": true}
+)
+
+type benchDecl struct {
+	file string
+	line int
+}
+
+var benchFuncDecl = regexp.MustCompile(`(?m)^func (Benchmark\w*)\(\w+ \*testing\.B\)`)
+
+func (a *BenchAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	return a.ExecuteContext(context.Background(), ctx)
+}
+
+// FindingsSeverity blocks on a blocking finding and warns on any other.
+func (*BenchAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		severity = "warning"
+	}
+	return severity
+}
+
+func (a *BenchAgent) ExecuteContext(runCtx context.Context, ctx AgentContext) (*AgentResult, error) {
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(BenchOptions))
+	if err != nil {
+		return nil, err
+	}
+	count, warnPct, blockPct := opts.Count, opts.WarningPct, opts.BlockingPct
+
+	changedDirs := make(map[string]bool)
+	for _, file := range ctx.Files {
+		if strings.HasSuffix(file, ".go") {
+			changedDirs[path.Dir(file)] = true
+		}
+	}
+	if len(changedDirs) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No Go files changed"})
+		return &res, nil
+	}
+
+	files, err := listFilesAtRevision(repoPath, ctx.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	benches := make(map[string]map[string]benchDecl) // package dir -> name -> decl
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_test.go") || !changedDirs[path.Dir(file)] {
+			continue
+		}
+		src, err := readFileAtRevision(repoPath, ctx.HeadRef, file)
+		if err != nil {
+			continue
+		}
+		for _, m := range benchFuncDecl.FindAllSubmatchIndex(src, -1) {
+			dir := path.Dir(file)
+			if benches[dir] == nil {
+				benches[dir] = make(map[string]benchDecl)
+			}
+			name := string(src[m[2]:m[3]])
+			benches[dir][name] = benchDecl{file: file, line: 1 + strings.Count(string(src[:m[0]]), "\n")}
+		}
+	}
+	if len(benches) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No benchmarks in the changed packages"})
+		return &res, nil
+	}
+
+	var report BenchReport
+	for dir := range benches {
+		report.Packages = append(report.Packages, dir)
+	}
+	sort.Strings(report.Packages)
+
+	headDir, cleanup, err := checkoutRevision(runCtx, repoPath, ctx.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	head, err := runBenchmarks(runCtx, headDir, report.Packages, benches, count, opts.Benchtime)
+	cleanup()
+	if err != nil {
+		return nil, fmt.Errorf("head benchmarks failed: %w", err)
+	}
+
+	var base map[string]map[string][]float64
+	if ctx.BaseRef != "" {
+		baseDir, cleanup, err := checkoutRevision(runCtx, repoPath, ctx.BaseRef)
+		if err == nil {
+			base, err = runBenchmarks(runCtx, baseDir, report.Packages, benches, count, opts.Benchtime)
+			cleanup()
+		}
+		if err != nil {
+			report.BaseError = err.Error()
+		}
+	}
+
+	var names []string
+	for name := range head {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dir, fn, _ := strings.Cut(name, " ")
+		decl := benches[dir][strings.SplitN(fn, "/", 2)[0]]
+		cmp := BenchComparison{Package: dir, Name: fn, File: decl.file, Line: decl.line}
+
+		for _, unit := range benchUnits {
+			samples, ok := head[name][unit]
+			if !ok {
+				continue
+			}
+			m := BenchMetric{Unit: unit, Head: summarizeBench(samples)}
+			if baseSamples, ok := base[name][unit]; ok {
+				b := summarizeBench(baseSamples)
+				delta := 0.0
+				if b.Median != 0 {
+					delta = (m.Head.Median - b.Median) / b.Median * 100
+				} else if m.Head.Median > 0 {
+					delta = 100
+				}
+				p := mannWhitneyP(baseSamples, samples)
+				m.Base, m.DeltaPct, m.PValue = &b, &delta, &p
+				m.Significant = p < benchAlpha
+
+				if m.Significant && benchRegressionUnits[unit] && delta >= warnPct {
+					severity := "warning"
+					if delta >= blockPct {
+						severity = "blocking"
+					}
+					cmp.Metrics = append(cmp.Metrics, m)
+					report.Findings = append(report.Findings, Finding{
+						Rule:     "bench/" + strings.ReplaceAll(unit, "/", "-"),
+						Severity: severity,
+						Message: fmt.Sprintf("%s %s regressed %+.1f%% (%s → %s, p=%.3f, n=%d+%d)",
+							fn, unit, delta, formatBenchValue(b.Median), formatBenchValue(m.Head.Median), p, b.Runs, m.Head.Runs),
+						File: decl.file,
+						Line: decl.line,
+					})
+					continue
+				}
+			}
+			cmp.Metrics = append(cmp.Metrics, m)
+		}
+		report.Benchmarks = append(report.Benchmarks, cmp)
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	res := a.CreateResult(AgentResult{Data: report, Findings: report.Findings, Severity: severity})
+	return &res, nil
+}
+
+// runBenchmarks runs the given benchmarks of each package count times in dir
+// and returns the samples keyed by "<package dir> <benchmark>" and unit.
+func runBenchmarks(runCtx context.Context, dir string, packages []string, benches map[string]map[string]benchDecl, count int, benchtime string) (map[string]map[string][]float64, error) {
+	results := make(map[string]map[string][]float64)
+	for _, pkg := range packages {
+		var names []string
+		for name := range benches[pkg] {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+		sort.Strings(names)
+
+		args := []string{"test", "-run", "^$", "-bench", "^(" + strings.Join(names, "|") + ")$", "-benchmem", "-count", strconv.Itoa(count)}
+		if benchtime != "" {
+			args = append(args, "-benchtime", benchtime)
+		}
+		args = append(args, "./"+pkg)
+		cmd := exec.CommandContext(runCtx, "go", args...)
+		cmd.Dir = dir
+		cmd.WaitDelay = cancelWaitDelay
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("go test -bench ./%s: %v: %s", pkg, err, lastLines(string(out), 20))
+		}
+		for name, units := range parseBenchOutput(string(out)) {
+			results[pkg+" "+name] = units
+		}
+	}
+	return results, nil
+}
+
+var benchProcsSuffix = regexp.MustCompile(`-\d+$`)
+
+// parseBenchOutput reads the result lines of go test -bench, e.g.
+// "BenchmarkX-8  1000  1234 ns/op  56 B/op  2 allocs/op".
+func parseBenchOutput(out string) map[string]map[string][]float64 {
+	results := make(map[string]map[string][]float64)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := benchProcsSuffix.ReplaceAllString(fields[0], "")
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				break
+			}
+			if results[name] == nil {
+				results[name] = make(map[string][]float64)
+			}
+			results[name][fields[i+1]] = append(results[name][fields[i+1]], v)
+		}
+	}
+	return results
+}
+
+func formatBenchValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
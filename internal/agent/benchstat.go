@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"math"
+	"sort"
+)
+
+// benchSummary describes the runs of one benchmark metric the way benchstat
+// does: the median and a distribution-free confidence interval around it.
+type benchSummary struct {
+	Median float64 `json:"median"`
+	Low    float64 `json:"low"`
+	High   float64 `json:"high"`
+	Runs   int     `json:"runs"`
+}
+
+// benchAlpha is the significance level for comparisons.
+const benchAlpha = 0.05
+
+// summarizeBench computes the median of xs and the 95% confidence interval of
+// the median from order statistics. With fewer than six runs the interval
+// falls back to the sample range.
+func summarizeBench(xs []float64) benchSummary {
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n == 0 {
+		return benchSummary{}
+	}
+
+	var median float64
+	if n%2 == 1 {
+		median = s[n/2]
+	} else {
+		median = (s[n/2-1] + s[n/2]) / 2
+	}
+
+	// Largest k with P(Binomial(n, 1/2) < k) <= alpha/2: the interval
+	// [s[k-1], s[n-k]] then covers the median with at least 95% confidence.
+	k := 0
+	cumulative := 0.0
+	for i := 0; i < n; i++ {
+		cumulative += binomialHalf(n, i)
+		if cumulative > benchAlpha/2 {
+			break
+		}
+		k = i + 1
+	}
+	if k == 0 {
+		k = 1
+	}
+	return benchSummary{Median: median, Low: s[k-1], High: s[n-k], Runs: n}
+}
+
+// binomialHalf returns P(X = k) for X ~ Binomial(n, 1/2).
+func binomialHalf(n, k int) float64 {
+	lg := func(x int) float64 { v, _ := math.Lgamma(float64(x) + 1); return v }
+	return math.Exp(lg(n) - lg(k) - lg(n-k) - float64(n)*math.Ln2)
+}
+
+// mannWhitneyP returns the two-sided p-value of the Mann-Whitney U test for
+// samples a and b. Small samples without ties use the exact distribution of
+// U, as benchstat does; otherwise the tie-corrected normal approximation.
+func mannWhitneyP(a, b []float64) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type value struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]value, 0, n1+n2)
+	for _, v := range a {
+		all = append(all, value{v, true})
+	}
+	for _, v := range b {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Rank with ties sharing their average rank.
+	rankSumA := 0.0
+	tieTerm := 0.0
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+	u := rankSumA - float64(n1*(n1+1))/2
+
+	if !ties && n1+n2 <= 40 {
+		return exactMannWhitneyP(n1, n2, int(math.Round(u)))
+	}
+
+	n := float64(n1 + n2)
+	mu := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactMannWhitneyP computes the two-sided p-value of U from its exact null
+// distribution, counting the rank arrangements of n1 and n2 values.
+func exactMannWhitneyP(n1, n2, u int) float64 {
+	maxU := n1 * n2
+	// counts[i][j][k]: arrangements of i a-values and j b-values with U = k,
+	// built up one sample size at a time.
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = make([]float64, maxU+1)
+		prev[j][0] = 1 // no a-values: U is always 0
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		for j := 0; j <= n2; j++ {
+			cur[j] = make([]float64, maxU+1)
+			for k := 0; k <= maxU; k++ {
+				// The largest value is either an a-value, beating all j
+				// b-values, or a b-value.
+				if k >= j {
+					cur[j][k] += prev[j][k-j]
+				}
+				if j > 0 {
+					cur[j][k] += cur[j-1][k]
+				}
+			}
+		}
+		prev = cur
+	}
+
+	dist := prev[n2]
+	total, lower, upper := 0.0, 0.0, 0.0
+	for k, c := range dist {
+		total += c
+		if k <= u {
+			lower += c
+		}
+		if k >= u {
+			upper += c
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
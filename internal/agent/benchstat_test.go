@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyP(t *testing.T) {
+	// Expected values are R's wilcox.test(a, b)$p.value, which uses the exact
+	// distribution without ties and the continuity-corrected normal
+	// approximation with them, as benchstat does.
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{
+			name: "separated n=5+5",
+			a:    []float64{1, 2, 3, 4, 5},
+			b:    []float64{6, 7, 8, 9, 10},
+			want: 0.007937, // benchstat: p=0.008 n=5+5
+		},
+		{
+			name: "separated n=3+3 cannot be significant",
+			a:    []float64{1, 2, 3},
+			b:    []float64{4, 5, 6},
+			want: 0.1, // benchstat: ~ (p=0.100 n=3+3)
+		},
+		{
+			name: "separated n=4+3",
+			a:    []float64{1, 2, 3, 4},
+			b:    []float64{5, 6, 7},
+			want: 0.05714,
+		},
+		{
+			name: "order does not matter",
+			a:    []float64{10, 9, 8, 7, 6},
+			b:    []float64{5, 4, 3, 2, 1},
+			want: 0.007937,
+		},
+		{
+			name: "overlapping, R documentation example",
+			a:    []float64{0.80, 0.83, 1.89, 1.04, 1.45, 1.38, 1.91, 1.64, 0.73, 1.46},
+			b:    []float64{1.15, 0.88, 0.90, 0.74, 1.21},
+			want: 0.2544,
+		},
+		{
+			name: "ties use the normal approximation",
+			a:    []float64{1, 2, 2, 3, 4},
+			b:    []float64{2, 3, 5, 6, 6},
+			want: 0.1105,
+		},
+		{
+			name: "ties across samples",
+			a:    []float64{10, 10, 11, 12},
+			b:    []float64{10, 12, 13, 14, 14},
+			want: 0.1316,
+		},
+		{
+			name: "identical samples",
+			a:    []float64{5, 5, 5},
+			b:    []float64{5, 5, 5},
+			want: 1,
+		},
+		{
+			name: "zero allocations on both sides",
+			a:    []float64{0, 0, 0, 0, 0},
+			b:    []float64{0, 0, 0, 0, 0},
+			want: 1,
+		},
+		{
+			name: "zero allocations against one",
+			a:    []float64{0, 0, 0, 0, 0},
+			b:    []float64{1, 1, 1, 1, 1},
+			want: 0.003977, // every value tied within its sample
+		},
+		{
+			name: "empty sample",
+			a:    nil,
+			b:    []float64{1, 2, 3},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mannWhitneyP(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 5e-4 {
+				t.Errorf("mannWhitneyP() = %.5f, want %.5f", got, tt.want)
+			}
+			if back := mannWhitneyP(tt.b, tt.a); math.Abs(back-got) > 1e-12 {
+				t.Errorf("mannWhitneyP() is not symmetric: %.5f and %.5f", got, back)
+			}
+		})
+	}
+}
+
+func TestExactMannWhitneyP(t *testing.T) {
+	tests := []struct {
+		n1, n2, u int
+		want      float64
+	}{
+		{1, 1, 0, 1},
+		{2, 2, 0, 2.0 / 6},
+		{3, 3, 0, 2.0 / 20},
+		{3, 3, 9, 2.0 / 20},
+		{3, 3, 4, 1},
+		{5, 5, 0, 2.0 / 252},
+		{5, 5, 1, 4.0 / 252},
+		{10, 5, 35, 0.2544},
+	}
+	for _, tt := range tests {
+		if got := exactMannWhitneyP(tt.n1, tt.n2, tt.u); math.Abs(got-tt.want) > 5e-5 {
+			t.Errorf("exactMannWhitneyP(%d, %d, %d) = %.5f, want %.5f", tt.n1, tt.n2, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeBench(t *testing.T) {
+	tests := []struct {
+		name string
+		xs   []float64
+		want benchSummary
+	}{
+		{
+			name: "empty",
+			want: benchSummary{},
+		},
+		{
+			name: "single run",
+			xs:   []float64{7},
+			want: benchSummary{Median: 7, Low: 7, High: 7, Runs: 1},
+		},
+		{
+			name: "n<6 falls back to the range",
+			xs:   []float64{5, 1, 4, 2, 3},
+			want: benchSummary{Median: 3, Low: 1, High: 5, Runs: 5},
+		},
+		{
+			name: "n=6 interval is still the range",
+			xs:   []float64{6, 1, 5, 2, 4, 3},
+			want: benchSummary{Median: 3.5, Low: 1, High: 6, Runs: 6},
+		},
+		{
+			name: "n=10 interval drops one run on each side",
+			xs:   []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+			want: benchSummary{Median: 5.5, Low: 2, High: 9, Runs: 10},
+		},
+		{
+			name: "n=20 interval drops five runs on each side",
+			xs:   []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			want: benchSummary{Median: 10.5, Low: 6, High: 15, Runs: 20},
+		},
+		{
+			name: "zero median",
+			xs:   []float64{0, 0, 0, 0, 0, 1},
+			want: benchSummary{Median: 0, Low: 0, High: 1, Runs: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeBench(tt.xs); got != tt.want {
+				t.Errorf("summarizeBench() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
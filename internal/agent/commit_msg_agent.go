@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+)
+
+const (
+	commitReviewSystemPrompt  = "You review commit messages. Only flag messages that are misleading or miss the main change, not stylistic issues."
+	commitSuggestSystemPrompt = "You write clear, concise commit messages."
+)
+
+type CommitMsgAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewCommitMsgAgent(cfg *config.Config) Agent {
+	return &CommitMsgAgent{
+		BaseAgent: BaseAgent{
+			id:          "commit-msg",
+			description: "Validates commit messages against Conventional Commits",
+			model:       cfg.Models.Primary,
+		},
+		cfg: cfg,
+	}
+}
+
+func (*CommitMsgAgent) Details() AgentDetails {
+	return AgentDetails{Prompts: map[string]string{"review": commitReviewSystemPrompt, "suggest": commitSuggestSystemPrompt}}
+}
+
+// CommitMsgOptions configures the commit-msg agent under
+// agents.commit-msg.options. The defaults are the Conventional Commits types.
+type CommitMsgOptions struct {
+	Types            []string `mapstructure:"types" yaml:"types"`
+	Scopes           []string `mapstructure:"scopes" yaml:"scopes"`
+	RequireScope     bool     `mapstructure:"require_scope" yaml:"require_scope"`
+	MaxSubjectLength int      `mapstructure:"max_subject_length" yaml:"max_subject_length"`
+	RequireBody      bool     `mapstructure:"require_body" yaml:"require_body"`
+	// CheckDiff asks the model whether the message describes the staged diff.
+	CheckDiff bool `mapstructure:"check_diff" yaml:"check_diff"`
+}
+
+func (o CommitMsgOptions) Validate() error {
+	if len(o.Types) == 0 {
+		return errors.New("types must not be empty")
+	}
+	if o.MaxSubjectLength <= 0 {
+		return errors.New("max_subject_length must be positive")
+	}
+	return nil
+}
+
+func (*CommitMsgAgent) DefaultOptions() any {
+	return CommitMsgOptions{
+		Types:            []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"},
+		MaxSubjectLength: 72,
+	}
+}
+
+var conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()\r\n]+)\))?(!)?: (\S.*)$`)
+
+type CommitMessageCheck struct {
+	Commit   string    `json:"commit,omitempty"`
+	Subject  string    `json:"subject"`
+	Valid    bool      `json:"valid"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+type CommitMsgReport struct {
+	Messages   []CommitMessageCheck `json:"messages,omitempty"`
+	Suggestion string               `json:"suggestion,omitempty"`
+	Findings   []Finding            `json:"-"`
+}
+
+func (a *CommitMsgAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	if ctx.Suggest {
+		return a.suggest(ctx)
+	}
+
+	var messages []Commit
+	if ctx.CommitMessage != "" {
+		messages = append(messages, Commit{Message: ctx.CommitMessage})
+	}
+	messages = append(messages, ctx.Commits...)
+	if len(messages) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No commit message to check; use --message-file or --base"})
+		return &res, nil
+	}
+
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(CommitMsgOptions))
+	if err != nil {
+		return nil, err
+	}
+
+	var report CommitMsgReport
+	tokensUsed := 0
+	for _, m := range messages {
+		check := a.validate(m, opts)
+		// Only the message being committed is compared with the staged diff.
+		if opts.CheckDiff && m.Hash == "" && ctx.Diff != "" {
+			finding, tokens := a.checkAgainstDiff(m.Message, ctx.Diff)
+			tokensUsed += tokens
+			if finding != nil {
+				check.Findings = append(check.Findings, *finding)
+			}
+		}
+		report.Messages = append(report.Messages, check)
+		report.Findings = append(report.Findings, check.Findings...)
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	res := a.CreateResult(AgentResult{
+		Data:       report,
+		Findings:   report.Findings,
+		Severity:   severity,
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+	})
+	return &res, nil
+}
+
+// FindingsSeverity blocks on a blocking finding and warns on any other.
+func (*CommitMsgAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		severity = "warning"
+	}
+	return severity
+}
+
+// validate checks a message against the Conventional Commits header format
+// and the configured rules.
+func (a *CommitMsgAgent) validate(c Commit, rules CommitMsgOptions) CommitMessageCheck {
+	subject, body, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	check := CommitMessageCheck{Commit: c.Hash, Subject: subject}
+	add := func(rule, severity, msg string) {
+		check.Findings = append(check.Findings, Finding{Rule: rule, Severity: severity, Message: msg})
+	}
+
+	// Messages generated by git itself are exempt.
+	if strings.HasPrefix(subject, "Merge ") || strings.HasPrefix(subject, "Revert \"") ||
+		strings.HasPrefix(subject, "fixup! ") || strings.HasPrefix(subject, "squash! ") {
+		check.Valid = true
+		return check
+	}
+
+	m := conventionalHeader.FindStringSubmatch(subject)
+	if m == nil {
+		add("conventional-format", "blocking", fmt.Sprintf("Subject %q does not follow \"type(scope): description\"", subject))
+		return check
+	}
+	typ, scope := m[1], m[2]
+
+	if !containsString(rules.Types, typ) {
+		add("commit-type", "blocking", fmt.Sprintf("Type %q is not one of: %s", typ, strings.Join(rules.Types, ", ")))
+	}
+	if scope == "" && rules.RequireScope {
+		add("commit-scope", "blocking", "A scope is required, e.g. \"feat(cli): ...\"")
+	}
+	if scope != "" && len(rules.Scopes) > 0 && !containsString(rules.Scopes, scope) {
+		add("commit-scope", "blocking", fmt.Sprintf("Scope %q is not one of: %s", scope, strings.Join(rules.Scopes, ", ")))
+	}
+
+	if len([]rune(subject)) > rules.MaxSubjectLength {
+		add("subject-length", "warning", fmt.Sprintf("Subject is %d characters, limit is %d", len([]rune(subject)), rules.MaxSubjectLength))
+	}
+	if strings.HasSuffix(subject, ".") {
+		add("subject-period", "warning", "Subject should not end with a period")
+	}
+
+	if body != "" && !strings.HasPrefix(body, "\n") {
+		add("body-separator", "warning", "Separate the subject from the body with a blank line")
+	}
+	if rules.RequireBody && strings.TrimSpace(body) == "" {
+		add("body-required", "warning", "A message body explaining the change is required")
+	}
+
+	check.Valid = len(check.Findings) == 0
+	return check
+}
+
+// checkAgainstDiff asks the model whether message describes diff. Provider
+// errors are not fatal: the format checks still stand on their own.
+func (a *CommitMsgAgent) checkAgainstDiff(message, diff string) (*Finding, int) {
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return nil, 0
+	}
+
+	prompt := fmt.Sprintf("Commit message:\n%s\n\nStaged diff:\n%s\n\nDoes the commit message accurately describe the diff? Respond JSON with { \"matches\": true, \"explanation\": \"\" }", message, diff)
+	response, err := p.Complete(prompt, commitReviewSystemPrompt, true)
+	if err != nil {
+		return nil, 0
+	}
+	tokens := len(prompt)/4 + len(response)/4
+
+	var verdict struct {
+		Matches     bool   `json:"matches"`
+		Explanation string `json:"explanation"`
+	}
+	if err := json.Unmarshal([]byte(response), &verdict); err != nil || verdict.Matches {
+		return nil, tokens
+	}
+	return &Finding{Rule: "message-mismatch", Severity: "warning", Message: "Message does not describe the diff: " + verdict.Explanation}, tokens
+}
+
+// suggest drafts a Conventional Commits message for the staged diff.
+func (a *CommitMsgAgent) suggest(ctx AgentContext) (*AgentResult, error) {
+	if ctx.Diff == "" {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No diff available"})
+		return &res, nil
+	}
+
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(CommitMsgOptions))
+	if err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf("Write a commit message for the following diff using the Conventional Commits format \"type(scope): description\". Allowed types: %s. Keep the subject under %d characters and add a short body explaining why if the change is not trivial. Reply with the message only.\n\n%s", strings.Join(opts.Types, ", "), opts.MaxSubjectLength, ctx.Diff)
+	response, err := p.Complete(prompt, commitSuggestSystemPrompt, false)
+	if err != nil {
+		return nil, fmt.Errorf("commit message suggestion failed: %w", err)
+	}
+	suggestion := strings.TrimSpace(strings.Trim(strings.TrimSpace(response), "`"))
+	tokensUsed := len(prompt)/4 + len(response)/4
+
+	res := a.CreateResult(AgentResult{
+		Data:       CommitMsgReport{Suggestion: suggestion},
+		Severity:   "info",
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+		Artifacts:  []AgentArtifact{{Type: "commit-message", Content: suggestion}},
+	})
+	return &res, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,443 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/autodevopsai/verifier-go/internal/storage"
+)
+
+type ComplexityAgent struct {
+	BaseAgent
+	cfg     *config.Config
+	metrics *storage.MetricsStore
+}
+
+func NewComplexityAgent(cfg *config.Config) Agent {
+	return &ComplexityAgent{
+		BaseAgent: BaseAgent{id: "complexity", description: "Complexity and maintainability metrics for changed functions", model: "none"},
+		cfg:       cfg,
+		metrics:   storage.NewMetricsStore(),
+	}
+}
+
+func (*ComplexityAgent) Details() AgentDetails {
+	var exts []string
+	for _, analyzer := range complexityAnalyzers {
+		exts = append(exts, analyzer.Extensions()...)
+	}
+	return AgentDetails{Languages: extensionLanguages(exts...)}
+}
+
+// ComplexityOptions holds the per-function limits of the complexity agent,
+// under agents.complexity.options.
+type ComplexityOptions struct {
+	MaxCyclomatic int `mapstructure:"max_cyclomatic" yaml:"max_cyclomatic"`
+	MaxCognitive  int `mapstructure:"max_cognitive" yaml:"max_cognitive"`
+	MaxLength     int `mapstructure:"max_length" yaml:"max_length"`
+	MaxNesting    int `mapstructure:"max_nesting" yaml:"max_nesting"`
+	MaxParams     int `mapstructure:"max_params" yaml:"max_params"`
+}
+
+func (o ComplexityOptions) Validate() error {
+	if o.MaxCyclomatic <= 0 || o.MaxCognitive <= 0 || o.MaxLength <= 0 || o.MaxNesting <= 0 || o.MaxParams <= 0 {
+		return errors.New("limits must be positive")
+	}
+	return nil
+}
+
+func (*ComplexityAgent) DefaultOptions() any {
+	return ComplexityOptions{MaxCyclomatic: 10, MaxCognitive: 15, MaxLength: 60, MaxNesting: 4, MaxParams: 5}
+}
+
+// FunctionComplexity holds the metrics of one function.
+type FunctionComplexity struct {
+	Name       string `json:"name"`
+	Line       int    `json:"line"`
+	EndLine    int    `json:"end_line"`
+	Cyclomatic int    `json:"cyclomatic"`
+	Cognitive  int    `json:"cognitive"`
+	Length     int    `json:"length"`
+	Nesting    int    `json:"nesting"`
+	Params     int    `json:"params"`
+}
+
+// ComplexityAnalyzer computes function metrics for the files of one language.
+type ComplexityAnalyzer interface {
+	Extensions() []string
+	Analyze(file string, src []byte) ([]FunctionComplexity, error)
+}
+
+var complexityAnalyzers = []ComplexityAnalyzer{goComplexityAnalyzer{}}
+
+// RegisterComplexityAnalyzer adds an analyzer, e.g. for another language.
+func RegisterComplexityAnalyzer(analyzer ComplexityAnalyzer) {
+	complexityAnalyzers = append(complexityAnalyzers, analyzer)
+}
+
+type FunctionComplexityChange struct {
+	File string              `json:"file"`
+	Head FunctionComplexity  `json:"head"`
+	Base *FunctionComplexity `json:"base,omitempty"`
+}
+
+type ComplexityReport struct {
+	Functions []FunctionComplexityChange `json:"functions"`
+	Findings  []Finding                  `json:"-"`
+}
+
+func (a *ComplexityAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	limits, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(ComplexityOptions))
+	if err != nil {
+		return nil, err
+	}
+	changed, err := patch.ChangedLines(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	var files []string
+	for file := range changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var report ComplexityReport
+	var samples []storage.FunctionMetric
+	now := time.Now()
+	revision := ctx.HeadRef
+	if revision == "" {
+		revision = "index"
+	}
+
+	for _, file := range files {
+		analyzer := complexityAnalyzerFor(file)
+		if analyzer == nil {
+			continue
+		}
+		headSrc, err := readChangedFile(repoPath, ctx.HeadRef, file)
+		if err != nil {
+			continue
+		}
+		head, err := analyzer.Analyze(file, headSrc)
+		if err != nil {
+			continue // unparsable files are the linters' concern
+		}
+
+		baseFuncs := make(map[string]FunctionComplexity)
+		if ctx.BaseRef != "" {
+			baseSrc, err := readFileAtRevision(repoPath, ctx.BaseRef, file)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			if err == nil {
+				base, _ := analyzer.Analyze(file, baseSrc)
+				for _, fn := range base {
+					baseFuncs[fn.Name] = fn
+				}
+			}
+		}
+
+		for _, fn := range head {
+			if !anyLineIn(changed[file], fn.Line, fn.EndLine) {
+				continue
+			}
+			change := FunctionComplexityChange{File: file, Head: fn}
+			if base, ok := baseFuncs[fn.Name]; ok {
+				change.Base = &base
+			}
+			report.Functions = append(report.Functions, change)
+			report.Findings = append(report.Findings, regressions(change, limits)...)
+			samples = append(samples, storage.FunctionMetric{
+				Timestamp:  now,
+				Revision:   revision,
+				File:       file,
+				Function:   fn.Name,
+				Cyclomatic: fn.Cyclomatic,
+				Cognitive:  fn.Cognitive,
+				Length:     fn.Length,
+				Nesting:    fn.Nesting,
+				Params:     fn.Params,
+			})
+		}
+	}
+
+	if len(report.Functions) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No changed functions to analyze"})
+		return &res, nil
+	}
+	_ = a.metrics.RecordComplexity(samples)
+
+	severity := a.FindingsSeverity(report.Findings)
+	res := a.CreateResult(AgentResult{Data: report, Findings: report.Findings, Severity: severity})
+	return &res, nil
+}
+
+// FindingsSeverity warns when there are findings.
+func (*ComplexityAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	if len(findings) > 0 {
+		severity = "warning"
+	}
+	return severity
+}
+
+// regressions reports each metric that is over its limit and either belongs
+// to a new function or got worse than in the base revision.
+func regressions(c FunctionComplexityChange, limits ComplexityOptions) []Finding {
+	checks := []struct {
+		rule, label string
+		head, limit int
+		base        func(FunctionComplexity) int
+	}{
+		{"cyclomatic", "Cyclomatic complexity", c.Head.Cyclomatic, limits.MaxCyclomatic, func(f FunctionComplexity) int { return f.Cyclomatic }},
+		{"cognitive", "Cognitive complexity", c.Head.Cognitive, limits.MaxCognitive, func(f FunctionComplexity) int { return f.Cognitive }},
+		{"length", "Length", c.Head.Length, limits.MaxLength, func(f FunctionComplexity) int { return f.Length }},
+		{"nesting", "Nesting depth", c.Head.Nesting, limits.MaxNesting, func(f FunctionComplexity) int { return f.Nesting }},
+		{"params", "Parameter count", c.Head.Params, limits.MaxParams, func(f FunctionComplexity) int { return f.Params }},
+	}
+
+	var findings []Finding
+	for _, check := range checks {
+		if check.head <= check.limit {
+			continue
+		}
+		msg := fmt.Sprintf("%s of %s is %d (limit %d)", check.label, c.Head.Name, check.head, check.limit)
+		if c.Base != nil {
+			before := check.base(*c.Base)
+			if check.head <= before {
+				continue // already over the limit and not made worse
+			}
+			msg = fmt.Sprintf("%s of %s rose from %d to %d (limit %d)", check.label, c.Head.Name, before, check.head, check.limit)
+		}
+		findings = append(findings, Finding{
+			Rule:     "complexity/" + check.rule,
+			Severity: "warning",
+			Message:  msg,
+			File:     c.File,
+			Line:     c.Head.Line,
+			EndLine:  c.Head.EndLine,
+		})
+	}
+	return findings
+}
+
+func complexityAnalyzerFor(file string) ComplexityAnalyzer {
+	ext := filepath.Ext(file)
+	for _, a := range complexityAnalyzers {
+		for _, e := range a.Extensions() {
+			if e == ext {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// goComplexityAnalyzer measures Go functions with go/ast.
+type goComplexityAnalyzer struct{}
+
+func (goComplexityAnalyzer) Extensions() []string { return []string{".go"} }
+
+func (goComplexityAnalyzer) Analyze(file string, src []byte) ([]FunctionComplexity, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var funcs []FunctionComplexity
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		start, end := fset.Position(fd.Pos()).Line, fset.Position(fd.End()).Line
+		cog := &cognitiveCounter{}
+		cog.walk(fd.Body, 0)
+		funcs = append(funcs, FunctionComplexity{
+			Name:       funcDeclName(fd),
+			Line:       start,
+			EndLine:    end,
+			Cyclomatic: cyclomatic(fd.Body),
+			Cognitive:  cog.score,
+			Length:     end - start + 1,
+			Nesting:    cog.maxNesting,
+			Params:     countParams(fd.Type.Params),
+		})
+	}
+	return funcs, nil
+}
+
+// cyclomatic counts the independent paths through a function body: one plus
+// each branch point and short-circuit operator.
+func cyclomatic(body *ast.BlockStmt) int {
+	n := 1
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch x := node.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			n++
+		case *ast.CaseClause:
+			if x.List != nil {
+				n++
+			}
+		case *ast.CommClause:
+			if x.Comm != nil {
+				n++
+			}
+		case *ast.BinaryExpr:
+			if x.Op == token.LAND || x.Op == token.LOR {
+				n++
+			}
+		}
+		return true
+	})
+	return n
+}
+
+// cognitiveCounter implements the cognitive complexity rules: control flow
+// breaks cost one plus their nesting level, "else" branches and labelled
+// jumps cost one, and each run of mixed && / || operators costs one.
+type cognitiveCounter struct {
+	score      int
+	maxNesting int
+}
+
+func (c *cognitiveCounter) nest(n ast.Node, nesting int) {
+	if nesting+1 > c.maxNesting {
+		c.maxNesting = nesting + 1
+	}
+	c.walk(n, nesting+1)
+}
+
+func (c *cognitiveCounter) walk(n ast.Node, nesting int) {
+	if n == nil {
+		return
+	}
+	switch x := n.(type) {
+	case *ast.IfStmt:
+		c.score += 1 + nesting
+		c.ifChain(x, nesting)
+		return
+	case *ast.ForStmt:
+		c.score += 1 + nesting
+		c.walk(x.Init, nesting)
+		c.walk(x.Cond, nesting)
+		c.walk(x.Post, nesting)
+		c.nest(x.Body, nesting)
+		return
+	case *ast.RangeStmt:
+		c.score += 1 + nesting
+		c.walk(x.X, nesting)
+		c.nest(x.Body, nesting)
+		return
+	case *ast.SwitchStmt:
+		c.score += 1 + nesting
+		c.walk(x.Init, nesting)
+		c.walk(x.Tag, nesting)
+		c.nest(x.Body, nesting)
+		return
+	case *ast.TypeSwitchStmt:
+		c.score += 1 + nesting
+		c.walk(x.Init, nesting)
+		c.nest(x.Body, nesting)
+		return
+	case *ast.SelectStmt:
+		c.score += 1 + nesting
+		c.nest(x.Body, nesting)
+		return
+	case *ast.FuncLit:
+		c.nest(x.Body, nesting)
+		return
+	case *ast.BranchStmt:
+		if x.Label != nil || x.Tok == token.GOTO {
+			c.score++
+		}
+		return
+	case *ast.BinaryExpr:
+		if x.Op == token.LAND || x.Op == token.LOR {
+			var ops []token.Token
+			var operands []ast.Expr
+			flattenLogical(x, &ops, &operands)
+			c.score++
+			for i := 1; i < len(ops); i++ {
+				if ops[i] != ops[i-1] {
+					c.score++
+				}
+			}
+			for _, operand := range operands {
+				c.walk(operand, nesting)
+			}
+			return
+		}
+	}
+
+	// Walk the immediate children.
+	ast.Inspect(n, func(child ast.Node) bool {
+		if child == n {
+			return true
+		}
+		if child != nil {
+			c.walk(child, nesting)
+		}
+		return false
+	})
+}
+
+// ifChain scores an if statement and its else-if/else branches. The leading
+// if has already been charged by the caller.
+func (c *cognitiveCounter) ifChain(x *ast.IfStmt, nesting int) {
+	c.walk(x.Init, nesting)
+	c.walk(x.Cond, nesting)
+	c.nest(x.Body, nesting)
+	switch e := x.Else.(type) {
+	case *ast.IfStmt:
+		c.score++
+		c.ifChain(e, nesting)
+	case *ast.BlockStmt:
+		c.score++
+		c.nest(e, nesting)
+	}
+}
+
+// flattenLogical lists the && and || operators of an expression in source
+// order, along with the operands between them. Parentheses start a new
+// sequence, so parenthesised groups are returned as operands.
+func flattenLogical(e ast.Expr, ops *[]token.Token, operands *[]ast.Expr) {
+	if b, ok := e.(*ast.BinaryExpr); ok && (b.Op == token.LAND || b.Op == token.LOR) {
+		flattenLogical(b.X, ops, operands)
+		*ops = append(*ops, b.Op)
+		flattenLogical(b.Y, ops, operands)
+		return
+	}
+	*operands = append(*operands, e)
+}
+
+func countParams(fields *ast.FieldList) int {
+	if fields == nil {
+		return 0
+	}
+	n := 0
+	for _, f := range fields.List {
+		if len(f.Names) == 0 {
+			n++
+		} else {
+			n += len(f.Names)
+		}
+	}
+	return n
+}
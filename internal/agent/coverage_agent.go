@@ -0,0 +1,511 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+)
+
+type CoverageAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewCoverageAgent(cfg *config.Config) Agent {
+	return &CoverageAgent{
+		BaseAgent: BaseAgent{id: "coverage", description: "Test coverage delta between base and head", model: "none"},
+		cfg:       cfg,
+	}
+}
+
+func (a *CoverageAgent) Details() AgentDetails {
+	opts, _ := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(CoverageOptions))
+	var langs []string
+	for _, tool := range opts.tools() {
+		langs = append(langs, tool.Language)
+	}
+	return AgentDetails{Languages: langs}
+}
+
+// DefaultThresholds falls back to thresholds.coverage_delta, which
+// config.Load defaults to config.DefaultCoverageDelta.
+func (a *CoverageAgent) DefaultThresholds() map[string]float64 {
+	return map[string]float64{"coverage_delta": float64(a.cfg.Thresholds.CoverageDelta)}
+}
+
+// CoverageTool describes how to collect line coverage for one language.
+// Tools are configured under agents.coverage.options.tools.
+type CoverageTool struct {
+	Language   string   `mapstructure:"language" yaml:"language"`
+	Extensions []string `mapstructure:"extensions" yaml:"extensions"`
+	// Command is run from the checkout root. "{profile}" is replaced with the
+	// path the tool must write its coverage profile to.
+	Command []string `mapstructure:"command" yaml:"command"`
+	// Format is the profile format: "go" (go test -coverprofile) or "lcov".
+	Format string `mapstructure:"format" yaml:"format"`
+}
+
+var coverageTools = []CoverageTool{
+	{
+		Language:   "Go",
+		Extensions: []string{".go"},
+		Command:    []string{"go", "test", "-coverprofile={profile}", "./..."},
+		Format:     "go",
+	},
+}
+
+// CoverageOptions configures the coverage agent under agents.coverage.options.
+type CoverageOptions struct {
+	// Tools adds coverage tools, e.g. for another language, or replaces the
+	// built-in tool for the same language.
+	Tools []CoverageTool `mapstructure:"tools" yaml:"tools"`
+}
+
+func (o CoverageOptions) Validate() error {
+	for i, tool := range o.Tools {
+		switch {
+		case tool.Language == "":
+			return fmt.Errorf("tools[%d]: language is required", i)
+		case len(tool.Extensions) == 0:
+			return fmt.Errorf("tools[%d] (%s): extensions are required", i, tool.Language)
+		case len(tool.Command) == 0:
+			return fmt.Errorf("tools[%d] (%s): command is required", i, tool.Language)
+		case tool.Format != "go" && tool.Format != "lcov":
+			return fmt.Errorf("tools[%d] (%s): unknown format %q, use go or lcov", i, tool.Language, tool.Format)
+		}
+	}
+	return nil
+}
+
+func (*CoverageAgent) DefaultOptions() any {
+	return CoverageOptions{}
+}
+
+// tools returns the built-in tools, with configured tools replacing those for
+// the same language, followed by the other configured tools.
+func (o CoverageOptions) tools() []CoverageTool {
+	var tools []CoverageTool
+	for _, tool := range coverageTools {
+		for _, t := range o.Tools {
+			if strings.EqualFold(t.Language, tool.Language) {
+				tool = t
+			}
+		}
+		tools = append(tools, tool)
+	}
+	for _, t := range o.Tools {
+		builtin := false
+		for _, tool := range coverageTools {
+			builtin = builtin || strings.EqualFold(t.Language, tool.Language)
+		}
+		if !builtin {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+type PackageCoverage struct {
+	Package     string   `json:"package"`
+	BasePercent *float64 `json:"base_percent,omitempty"`
+	HeadPercent float64  `json:"head_percent"`
+}
+
+type CoverageReport struct {
+	Language       string            `json:"language"`
+	BasePercent    *float64          `json:"base_percent,omitempty"`
+	HeadPercent    float64           `json:"head_percent"`
+	Delta          *float64          `json:"delta,omitempty"`
+	ChangedLines   int               `json:"changed_lines"`
+	ChangedCovered int               `json:"changed_covered"`
+	ChangedPercent float64           `json:"changed_percent"`
+	Packages       []PackageCoverage `json:"packages"`
+	BaseError      string            `json:"base_error,omitempty"`
+}
+
+type CoverageAnalysis struct {
+	Reports  []CoverageReport `json:"reports"`
+	Findings []Finding        `json:"-"`
+}
+
+// coverBlock is a range of lines and the statements within it, as reported by
+// a coverage profile.
+type coverBlock struct {
+	StartLine int
+	EndLine   int
+	Stmts     int
+	Count     int
+}
+
+// coverProfile maps repo-relative file paths to their coverage blocks.
+type coverProfile map[string][]coverBlock
+
+func (a *CoverageAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	return a.ExecuteContext(context.Background(), ctx)
+}
+
+func (a *CoverageAgent) ExecuteContext(runCtx context.Context, ctx AgentContext) (*AgentResult, error) {
+	if len(ctx.Files) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No files to check"})
+		return &res, nil
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(CoverageOptions))
+	if err != nil {
+		return nil, err
+	}
+	changed, err := patch.ChangedLines(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	var analysis CoverageAnalysis
+	threshold := agentThreshold(a.cfg, a.ID(), "coverage_delta", a.DefaultThresholds()["coverage_delta"])
+	blocking := false
+	totalStmts, totalCovered := 0, 0
+
+	for _, tool := range opts.tools() {
+		if !anyFileMatches(ctx.Files, tool.Extensions) {
+			continue
+		}
+
+		headDir, cleanup, err := checkoutRevision(runCtx, repoPath, ctx.HeadRef)
+		if err != nil {
+			return nil, err
+		}
+		head, err := runCoverageTool(runCtx, tool, headDir)
+		cleanup()
+		if err != nil {
+			return nil, fmt.Errorf("%s coverage failed: %w", tool.Language, err)
+		}
+
+		report := CoverageReport{Language: tool.Language}
+		stmts, covered := head.totals("")
+		totalStmts += stmts
+		totalCovered += covered
+		report.HeadPercent = percent(covered, stmts)
+
+		var base coverProfile
+		if ctx.BaseRef != "" {
+			baseDir, cleanup, err := checkoutRevision(runCtx, repoPath, ctx.BaseRef)
+			if err == nil {
+				base, err = runCoverageTool(runCtx, tool, baseDir)
+				cleanup()
+			}
+			if err != nil {
+				report.BaseError = err.Error()
+			}
+		}
+		if base != nil {
+			bs, bc := base.totals("")
+			basePct := percent(bc, bs)
+			delta := report.HeadPercent - basePct
+			report.BasePercent = &basePct
+			report.Delta = &delta
+			if delta < threshold {
+				blocking = true
+			}
+		}
+
+		for _, pkg := range head.packages() {
+			s, c := head.totals(pkg)
+			pc := PackageCoverage{Package: pkg, HeadPercent: percent(c, s)}
+			if base != nil {
+				if bs, bc := base.totals(pkg); bs > 0 {
+					p := percent(bc, bs)
+					pc.BasePercent = &p
+				}
+			}
+			report.Packages = append(report.Packages, pc)
+		}
+
+		for _, file := range ctx.Files {
+			if !anyFileMatches([]string{file}, tool.Extensions) {
+				continue
+			}
+			executable, covered, uncovered := head.changedLineCoverage(file, changed[file])
+			report.ChangedLines += executable
+			report.ChangedCovered += covered
+			analysis.Findings = append(analysis.Findings, uncovered...)
+		}
+		report.ChangedPercent = percent(report.ChangedCovered, report.ChangedLines)
+		analysis.Reports = append(analysis.Reports, report)
+	}
+
+	if len(analysis.Reports) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No coverage tool for the changed files"})
+		return &res, nil
+	}
+
+	severity := "info"
+	if blocking {
+		severity = "blocking"
+	} else if len(analysis.Findings) > 0 {
+		severity = "warning"
+	}
+
+	res := a.CreateResult(AgentResult{
+		Score:    int(math.Round(percent(totalCovered, totalStmts))),
+		Data:     analysis,
+		Findings: analysis.Findings,
+		Severity: severity,
+	})
+	return &res, nil
+}
+
+// runCoverageTool runs tool in dir and parses the profile it writes. Test
+// failures are tolerated as long as a profile was produced.
+func runCoverageTool(runCtx context.Context, tool CoverageTool, dir string) (coverProfile, error) {
+	f, err := os.CreateTemp("", "verifier-cover-*.out")
+	if err != nil {
+		return nil, err
+	}
+	profilePath := f.Name()
+	f.Close()
+	defer os.Remove(profilePath)
+
+	args := make([]string, len(tool.Command))
+	for i, arg := range tool.Command {
+		args[i] = strings.ReplaceAll(arg, "{profile}", profilePath)
+	}
+	cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.WaitDelay = cancelWaitDelay
+	output, runErr := cmd.CombinedOutput()
+
+	if info, err := os.Stat(profilePath); err != nil || info.Size() == 0 {
+		if runErr == nil {
+			runErr = fmt.Errorf("no coverage profile written")
+		}
+		return nil, fmt.Errorf("%s: %v: %s", args[0], runErr, lastLines(string(output), 20))
+	}
+
+	switch tool.Format {
+	case "go":
+		return parseGoCoverProfile(profilePath, goModulePath(dir))
+	case "lcov":
+		return parseLCOV(profilePath, dir)
+	default:
+		return nil, fmt.Errorf("unsupported coverage format: %s", tool.Format)
+	}
+}
+
+// parseGoCoverProfile reads a profile written by go test -coverprofile.
+// Import paths are made repo-relative by stripping the module path.
+func parseGoCoverProfile(path, module string) (coverProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profile := make(coverProfile)
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// file.go:startLine.startCol,endLine.endCol numStmts count
+		colon := strings.LastIndexByte(line, ':')
+		fields := strings.Fields(line[colon+1:])
+		if colon < 0 || len(fields) != 3 {
+			return nil, fmt.Errorf("malformed coverage line: %q", line)
+		}
+		file := line[:colon]
+		if module != "" {
+			file = strings.TrimPrefix(strings.TrimPrefix(file, module), "/")
+		}
+		start, end, ok := strings.Cut(fields[0], ",")
+		if !ok {
+			return nil, fmt.Errorf("malformed coverage range: %q", line)
+		}
+		block := coverBlock{StartLine: leadingInt(start), EndLine: leadingInt(end)}
+		block.Stmts, _ = strconv.Atoi(fields[1])
+		block.Count, _ = strconv.Atoi(fields[2])
+
+		// Blocks repeat when a package is covered by several test binaries.
+		key := file + ":" + fields[0]
+		if i, ok := seen[key]; ok {
+			if block.Count > profile[file][i].Count {
+				profile[file][i].Count = block.Count
+			}
+			continue
+		}
+		seen[key] = len(profile[file])
+		profile[file] = append(profile[file], block)
+	}
+	return profile, scanner.Err()
+}
+
+// parseLCOV reads an lcov tracefile, treating every DA record as a
+// single-statement block.
+func parseLCOV(path, dir string) (coverProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profile := make(coverProfile)
+	var file string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			file = strings.TrimPrefix(line, "SF:")
+			if filepath.IsAbs(file) {
+				if rel, err := filepath.Rel(dir, file); err == nil {
+					file = rel
+				}
+			}
+			file = filepath.ToSlash(file)
+		case strings.HasPrefix(line, "DA:") && file != "":
+			parts := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(parts) < 2 {
+				continue
+			}
+			n, _ := strconv.Atoi(parts[0])
+			count, _ := strconv.Atoi(parts[1])
+			profile[file] = append(profile[file], coverBlock{StartLine: n, EndLine: n, Stmts: 1, Count: count})
+		case line == "end_of_record":
+			file = ""
+		}
+	}
+	return profile, scanner.Err()
+}
+
+// totals returns the statement and covered statement counts, restricted to
+// files in pkg when it is non-empty.
+func (p coverProfile) totals(pkg string) (stmts, covered int) {
+	for file, blocks := range p {
+		if pkg != "" && filepath.ToSlash(filepath.Dir(file)) != pkg {
+			continue
+		}
+		for _, b := range blocks {
+			stmts += b.Stmts
+			if b.Count > 0 {
+				covered += b.Stmts
+			}
+		}
+	}
+	return stmts, covered
+}
+
+func (p coverProfile) packages() []string {
+	set := make(map[string]bool)
+	for file := range p {
+		set[filepath.ToSlash(filepath.Dir(file))] = true
+	}
+	var pkgs []string
+	for pkg := range set {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// changedLineCoverage classifies the changed lines of file. Lines outside any
+// block are not executable and are ignored. Consecutive uncovered lines are
+// reported as a single finding.
+func (p coverProfile) changedLineCoverage(file string, lines []int) (executable, covered int, findings []Finding) {
+	blocks := p[file]
+	var current *Finding
+	for _, n := range lines {
+		inBlock, hit := false, false
+		for _, b := range blocks {
+			if b.Stmts > 0 && n >= b.StartLine && n <= b.EndLine {
+				inBlock = true
+				hit = hit || b.Count > 0
+			}
+		}
+		if !inBlock {
+			continue
+		}
+		executable++
+		if hit {
+			covered++
+			continue
+		}
+		if current != nil && current.EndLine == n-1 {
+			current.EndLine = n
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:     "uncovered-change",
+			Severity: "warning",
+			Message:  "Changed line is not covered by tests",
+			File:     file,
+			Line:     n,
+			EndLine:  n,
+		})
+		current = &findings[len(findings)-1]
+	}
+	return executable, covered, findings
+}
+
+// goModulePath returns the module path declared in dir/go.mod, if any.
+func goModulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+		}
+	}
+	return ""
+}
+
+func anyFileMatches(files []string, extensions []string) bool {
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		for _, e := range extensions {
+			if ext == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+func leadingInt(s string) int {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/osv"
+)
+
+type DepsAgent struct {
+	BaseAgent
+	cfg *config.Config
+	db  *osv.Database
+}
+
+func NewDepsAgent(cfg *config.Config) Agent {
+	return &DepsAgent{
+		BaseAgent: BaseAgent{id: "deps", description: "Checks new dependency versions against an offline OSV database", model: "none"},
+		cfg:       cfg,
+		db:        osv.NewDatabase(),
+	}
+}
+
+func (*DepsAgent) Details() AgentDetails {
+	seen := make(map[string]bool)
+	var ecosystems []string
+	for _, eco := range manifestEcosystems {
+		if !seen[eco] {
+			seen[eco] = true
+			ecosystems = append(ecosystems, eco)
+		}
+	}
+	sort.Strings(ecosystems)
+	return AgentDetails{Languages: ecosystems}
+}
+
+type DependencyVulnerability struct {
+	Dependency
+	Manifest string   `json:"manifest"`
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	FixedIn  []string `json:"fixed_in,omitempty"`
+}
+
+type DepsReport struct {
+	Checked         []Dependency              `json:"checked"`
+	Vulnerabilities []DependencyVulnerability `json:"vulnerabilities"`
+	Findings        []Finding                 `json:"-"`
+}
+
+func (a *DepsAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	var manifests []string
+	for _, file := range ctx.Files {
+		if isDependencyManifest(file) {
+			manifests = append(manifests, file)
+		}
+	}
+	if len(manifests) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No dependency manifests changed"})
+		return &res, nil
+	}
+	if !a.db.Exists() {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "OSV database not found; run 'verifier deps update-db <osv-export.zip>'"})
+		return &res, nil
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	var report DepsReport
+	seen := make(map[string]bool)
+	for _, manifest := range manifests {
+		// Without a base revision every pinned dependency counts as new.
+		var before []byte
+		if ctx.BaseRef != "" {
+			var err error
+			before, err = readFileAtRevision(repoPath, ctx.BaseRef, manifest)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read base %s: %w", manifest, err)
+			}
+		}
+		after, err := readFileAtRevision(repoPath, ctx.HeadRef, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", manifest, err)
+		}
+
+		deps, err := changedDependencies(manifest, before, after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", manifest, err)
+		}
+		sort.Slice(deps, func(i, j int) bool {
+			if deps[i].Name != deps[j].Name {
+				return deps[i].Name < deps[j].Name
+			}
+			return deps[i].Version < deps[j].Version
+		})
+
+		for _, dep := range deps {
+			key := dep.Ecosystem + " " + dep.Name + "@" + dep.Version
+			if seen[key] {
+				continue // go.mod and go.sum list the same modules
+			}
+			seen[key] = true
+			report.Checked = append(report.Checked, dep)
+
+			vulns, err := a.db.Lookup(dep.Ecosystem, dep.Name, dep.Version)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range vulns {
+				dv := DependencyVulnerability{
+					Dependency: dep,
+					Manifest:   manifest,
+					ID:         v.ID,
+					Aliases:    v.Aliases,
+					Summary:    v.Summary,
+					Severity:   v.Severity,
+					FixedIn:    v.FixedVersions(),
+				}
+				report.Vulnerabilities = append(report.Vulnerabilities, dv)
+				report.Findings = append(report.Findings, dv.finding())
+			}
+		}
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	res := a.CreateResult(AgentResult{Data: report, Findings: report.Findings, Severity: severity})
+	return &res, nil
+}
+
+// FindingsSeverity blocks on a blocking finding and warns on any other.
+func (*DepsAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		severity = "warning"
+	}
+	return severity
+}
+
+func (v DependencyVulnerability) finding() Finding {
+	ids := v.ID
+	if len(v.Aliases) > 0 {
+		ids += " (" + strings.Join(v.Aliases, ", ") + ")"
+	}
+	msg := fmt.Sprintf("%s@%s is affected by %s", v.Name, v.Version, ids)
+	if v.Summary != "" {
+		msg += ": " + v.Summary
+	}
+	if len(v.FixedIn) > 0 {
+		msg += ". Fixed in " + strings.Join(v.FixedIn, ", ")
+	} else {
+		msg += ". No fixed version available"
+	}
+
+	// GHSA-style severities are published as database_specific.severity.
+	severity := "warning"
+	switch strings.ToUpper(v.Severity) {
+	case "CRITICAL", "HIGH":
+		severity = "blocking"
+	case "LOW":
+		severity = "info"
+	}
+
+	return Finding{Rule: v.ID, Severity: severity, Message: msg, File: v.Manifest}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// AgentDetails is what an agent can tell about itself beyond the Agent
+// interface, for `verifier agents describe`.
+type AgentDetails struct {
+	// Languages the agent checks; empty means any.
+	Languages []string `json:"languages,omitempty"`
+	// Prompts are the system prompts and templates sent to the model.
+	Prompts map[string]string `json:"prompts,omitempty"`
+}
+
+// Describer is implemented by agents that report AgentDetails.
+type Describer interface {
+	Details() AgentDetails
+}
+
+// AgentInfo describes a registered agent.
+type AgentInfo struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Kind        string   `json:"kind"` // "builtin", "plugin", "wasm", "prompt"
+	Model       string   `json:"model"`
+	LLM         bool     `json:"llm"`
+	Enabled     bool     `json:"enabled"`
+	Timeout     string   `json:"timeout,omitempty"`
+	Include     []string `json:"include,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+	NewCode     string   `json:"new_code"`
+	// Thresholds and Options hold the effective values: the configured ones
+	// or the agent's defaults.
+	Thresholds map[string]float64 `json:"thresholds,omitempty"`
+	Options    []ConfigField      `json:"options,omitempty"`
+	AgentDetails
+}
+
+// ConfigField is one setting of an agent's config section.
+type ConfigField struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// DescribeAgent initializes the agent with the given ID and describes it with
+// its agents.<id> settings applied. Invalid options are reported as an error.
+func DescribeAgent(id string, cfg *config.Config) (AgentInfo, error) {
+	cfg = configFor(id, cfg)
+	a, err := GetAgent(id, cfg)
+	if err != nil {
+		return AgentInfo{}, err
+	}
+	settings := cfg.Agents[id]
+	info := AgentInfo{
+		ID:          id,
+		Description: a.Description(),
+		Kind:        AgentKind(id),
+		Model:       a.Model(),
+		LLM:         a.Model() != "none",
+		Enabled:     cfg.AgentEnabled(id),
+		Timeout:     settings.Timeout,
+		Include:     settings.Include,
+		Exclude:     settings.Exclude,
+		NewCode:     settings.NewCode,
+	}
+	if info.NewCode == "" {
+		info.NewCode = "all"
+	}
+	if d, ok := a.(Describer); ok {
+		info.AgentDetails = d.Details()
+	}
+	if t, ok := a.(Thresholder); ok {
+		info.Thresholds = t.DefaultThresholds()
+		for name := range info.Thresholds {
+			info.Thresholds[name] = agentThreshold(cfg, id, name, info.Thresholds[name])
+		}
+	}
+	if c, ok := a.(Configurable); ok {
+		opts := reflect.New(reflect.TypeOf(c.DefaultOptions()))
+		opts.Elem().Set(reflect.ValueOf(c.DefaultOptions()))
+		if err := decodeOptions(settings.Options, opts.Interface()); err != nil {
+			return info, fmt.Errorf("invalid agents.%s.options: %w", id, err)
+		}
+		info.Options = configFields("agents."+id+".options", opts)
+	}
+	return info, nil
+}
+
+// DefaultAgentSettings returns agents.<id> settings holding the default
+// options of every configurable agent, for writing a starting config.
+func DefaultAgentSettings(cfg *config.Config) (map[string]config.AgentSettings, error) {
+	settings := make(map[string]config.AgentSettings)
+	for _, id := range ListAgents() {
+		a, err := GetAgent(id, cfg)
+		if err != nil {
+			return nil, err
+		}
+		c, ok := a.(Configurable)
+		if !ok {
+			continue
+		}
+		// Round-trip through YAML so the options use their config keys.
+		data, err := yaml.Marshal(c.DefaultOptions())
+		if err != nil {
+			return nil, err
+		}
+		var opts map[string]any
+		if err := yaml.Unmarshal(data, &opts); err != nil {
+			return nil, err
+		}
+		settings[id] = config.AgentSettings{Options: opts}
+	}
+	return settings, nil
+}
+
+// configFields flattens a config struct into its settings, keyed by their
+// dotted YAML path under prefix.
+func configFields(prefix string, v reflect.Value) []ConfigField {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return []ConfigField{{Key: prefix, Type: typeName(v.Type()), Value: v.Interface()}}
+	}
+
+	var fields []ConfigField
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + "." + name
+		if f.Type.Kind() == reflect.Struct {
+			fields = append(fields, configFields(key, v.Field(i))...)
+			continue
+		}
+		fields = append(fields, ConfigField{Key: key, Type: typeName(f.Type), Value: v.Field(i).Interface()})
+	}
+	return fields
+}
+
+func typeName(t reflect.Type) string {
+	return strings.ReplaceAll(t.String(), "interface {}", "any")
+}
+
+// extensionLanguages names the languages of file extensions for AgentDetails.
+func extensionLanguages(exts ...string) []string {
+	seen := make(map[string]bool)
+	var langs []string
+	for _, ext := range exts {
+		lang := getLanguage(ext)
+		if lang == "Unknown" {
+			lang = fmt.Sprintf("%s files", ext)
+		}
+		if !seen[lang] {
+			seen[lang] = true
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
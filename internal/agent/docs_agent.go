@@ -0,0 +1,444 @@
+package agent
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+)
+
+const docsSystemPrompt = "You maintain a project's CHANGELOG. Describe changes from the user's point of view, briefly."
+
+type DocsAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewDocsAgent(cfg *config.Config) Agent {
+	return &DocsAgent{
+		BaseAgent: BaseAgent{
+			id:          "docs",
+			description: "Finds documentation made stale by a change and missing CHANGELOG entries",
+			model:       cfg.Models.Primary,
+		},
+		cfg: cfg,
+	}
+}
+
+func (*DocsAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"Go", "Markdown"}, Prompts: map[string]string{"changelog": docsSystemPrompt}}
+}
+
+// DocSymbol is a user-facing name declared in Go source: an exported
+// identifier, a CLI flag or command, or a config key.
+type DocSymbol struct {
+	Kind string `json:"kind"` // "identifier", "flag", "command", "config-key"
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+type DocsReport struct {
+	Removed  []DocSymbol `json:"removed,omitempty"`
+	Added    []DocSymbol `json:"added,omitempty"`
+	Findings []Finding   `json:"-"`
+	// Changelog holds the drafted CHANGELOG lines in --suggest mode.
+	Changelog string `json:"changelog,omitempty"`
+}
+
+const changelogFile = "CHANGELOG.md"
+
+func (a *DocsAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	diffs, err := patch.Parse(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+	if len(diffs) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No diff available"})
+		return &res, nil
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	// Symbols declared by the changed files before and after the change.
+	before := make(map[string]DocSymbol)
+	after := make(map[string]DocSymbol)
+	changelogChanged := false
+	for _, d := range diffs {
+		if d.Path() == changelogFile {
+			changelogChanged = true
+		}
+		if d.OldPath != "" && ctx.BaseRef != "" && isDocSource(d.OldPath) {
+			if src, err := readFileAtRevision(repoPath, ctx.BaseRef, d.OldPath); err == nil {
+				for _, s := range goDocSymbols(d.OldPath, src) {
+					before[s.Kind+" "+s.Name] = s
+				}
+			}
+		}
+		if d.NewPath != "" && isDocSource(d.NewPath) {
+			if src, err := readChangedFile(repoPath, ctx.HeadRef, d.NewPath); err == nil {
+				for _, s := range goDocSymbols(d.NewPath, src) {
+					after[s.Kind+" "+s.Name] = s
+				}
+			}
+		}
+	}
+
+	files, err := listFilesAtRevision(repoPath, ctx.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := scanDocTree(repoPath, ctx.HeadRef, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var report DocsReport
+	for key, s := range before {
+		if _, ok := after[key]; !ok && !tree.defines(s) {
+			report.Removed = append(report.Removed, s)
+		}
+	}
+	for key, s := range after {
+		if _, ok := before[key]; !ok && ctx.BaseRef != "" {
+			report.Added = append(report.Added, s)
+		}
+	}
+	sortDocSymbols(report.Removed)
+	sortDocSymbols(report.Added)
+
+	for _, s := range report.Removed {
+		re := tree.referencePattern(s)
+		for _, ref := range tree.docLines {
+			if re.MatchString(ref.text) {
+				report.Findings = append(report.Findings, Finding{
+					Rule:     "docs/stale-reference",
+					Severity: "warning",
+					Message:  fmt.Sprintf("References %s %s, which was removed or renamed in %s", s.Kind, displaySymbol(s), s.File),
+					File:     ref.file,
+					Line:     ref.line,
+				})
+			}
+		}
+	}
+
+	var artifacts []AgentArtifact
+	tokensUsed := 0
+	if tree.hasChangelog && !changelogChanged && userVisible(report) {
+		report.Findings = append(report.Findings, Finding{
+			Rule:     "docs/changelog",
+			Severity: "warning",
+			Message:  "User-visible change has no CHANGELOG entry",
+			File:     changelogFile,
+		})
+		if ctx.Suggest {
+			var draft string
+			draft, tokensUsed = a.draftChangelog(ctx, repoPath, report)
+			if draft != "" {
+				report.Changelog = draft
+				if content, err := readChangedFile(repoPath, ctx.HeadRef, changelogFile); err == nil {
+					artifacts = append(artifacts, AgentArtifact{Type: "patch", Path: changelogFile, Rule: "docs/changelog", Content: changelogPatch(string(content), draft)})
+				}
+			}
+		}
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	res := a.CreateResult(AgentResult{
+		Data:       report,
+		Findings:   report.Findings,
+		Severity:   severity,
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+		Artifacts:  artifacts,
+	})
+	return &res, nil
+}
+
+// FindingsSeverity warns when there are findings.
+func (*DocsAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	if len(findings) > 0 {
+		severity = "warning"
+	}
+	return severity
+}
+
+// draftChangelog asks the model for CHANGELOG lines in the style of the
+// existing Unreleased entries. A provider failure yields no draft.
+func (a *DocsAgent) draftChangelog(ctx AgentContext, repoPath string, report DocsReport) (string, int) {
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return "", 0
+	}
+
+	content, _ := readChangedFile(repoPath, ctx.HeadRef, changelogFile)
+	var changes strings.Builder
+	for _, s := range report.Removed {
+		fmt.Fprintf(&changes, "- removed %s %s\n", s.Kind, displaySymbol(s))
+	}
+	for _, s := range report.Added {
+		fmt.Fprintf(&changes, "- added %s %s\n", s.Kind, displaySymbol(s))
+	}
+	diff := ctx.Diff
+	if len(diff) > maxReviewContext {
+		diff = diff[:maxReviewContext]
+	}
+
+	prompt := fmt.Sprintf("Existing CHANGELOG:\n%s\n\nUser-visible symbol changes:\n%s\nDiff:\n%s\n\nWrite the bullet lines to add to the Unreleased section for this change, matching the style of the existing entries. Reply with the bullet lines only.", truncateLines(string(content), 40), changes.String(), diff)
+	response, err := p.Complete(prompt, docsSystemPrompt, false)
+	if err != nil {
+		return "", 0
+	}
+	tokens := len(prompt)/4 + len(response)/4
+
+	var lines []string
+	for _, l := range strings.Split(stripCodeFence(response), "\n") {
+		if l = strings.TrimSpace(l); strings.HasPrefix(l, "- ") || strings.HasPrefix(l, "* ") {
+			lines = append(lines, "- "+strings.TrimSpace(l[2:]))
+		}
+	}
+	return strings.Join(lines, "\n"), tokens
+}
+
+var unreleasedHeading = regexp.MustCompile(`(?i)^##\s*\[?unreleased`)
+
+// changelogPatch inserts entries at the end of the Unreleased section,
+// creating the section above the latest release when there is none.
+func changelogPatch(content, entries string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	section := -1
+	for i, l := range lines {
+		if unreleasedHeading.MatchString(l) {
+			section = i
+			break
+		}
+	}
+
+	if section < 0 {
+		for i, l := range lines {
+			if strings.HasPrefix(l, "## ") {
+				return patch.ReplaceLines(changelogFile, content, i+1, i, "## Unreleased\n\n"+entries+"\n\n")
+			}
+		}
+		return patch.ReplaceLines(changelogFile, content, len(lines)+1, len(lines), "\n## Unreleased\n\n"+entries+"\n")
+	}
+
+	last := -1
+	for i := section + 1; i < len(lines) && !strings.HasPrefix(lines[i], "## "); i++ {
+		if strings.HasPrefix(lines[i], "- ") || strings.HasPrefix(lines[i], "* ") {
+			last = i
+		}
+	}
+	if last < 0 {
+		// Empty section: keep a blank line on both sides of the entries.
+		at := min(section+2, len(lines))
+		return patch.ReplaceLines(changelogFile, content, at+1, at, entries+"\n\n")
+	}
+	return patch.ReplaceLines(changelogFile, content, last+2, last+1, entries+"\n")
+}
+
+func truncateLines(s string, n int) string {
+	lines := strings.SplitN(s, "\n", n+1)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// userVisible reports whether the change touches anything users see: CLI
+// flags, commands, config keys or the exported API of public packages.
+func userVisible(r DocsReport) bool {
+	for _, s := range append(r.Removed, r.Added...) {
+		if s.Kind != "identifier" || !isInternalPackage(path.Dir(s.File)) && !strings.HasPrefix(s.File, "cmd/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isDocSource(file string) bool {
+	return strings.HasSuffix(file, ".go") && !strings.HasSuffix(file, "_test.go") && !isVendoredPath(file)
+}
+
+var (
+	goFlagDecl    = regexp.MustCompile(`\.(?:Persistent)?Flags\(\)\.\w+\((?:&[\w.]+,\s*)?"([\w-]+)"`)
+	goCommandDecl = regexp.MustCompile(`Use:\s*"([\w-]+)`)
+	goConfigKey   = regexp.MustCompile(`mapstructure:"([\w-]+)"`)
+)
+
+// goDocSymbols lists the user-facing names a Go file declares.
+func goDocSymbols(file string, src []byte) []DocSymbol {
+	var symbols []DocSymbol
+	add := func(kind, name string) {
+		symbols = append(symbols, DocSymbol{Kind: kind, Name: name, File: file})
+	}
+
+	if f, err := parser.ParseFile(token.NewFileSet(), file, src, parser.SkipObjectResolution); err == nil {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Name.IsExported() {
+					add("identifier", d.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if s.Name.IsExported() {
+							add("identifier", s.Name.Name)
+						}
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							if n.IsExported() {
+								add("identifier", n.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for kind, re := range map[string]*regexp.Regexp{"flag": goFlagDecl, "command": goCommandDecl, "config-key": goConfigKey} {
+		for _, m := range re.FindAllSubmatch(src, -1) {
+			add(kind, string(m[1]))
+		}
+	}
+	return symbols
+}
+
+func displaySymbol(s DocSymbol) string {
+	if s.Kind == "flag" {
+		return "--" + s.Name
+	}
+	return s.Name
+}
+
+func sortDocSymbols(symbols []DocSymbol) {
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Kind != symbols[j].Kind {
+			return symbols[i].Kind < symbols[j].Kind
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+}
+
+type docLine struct {
+	file string
+	line int
+	text string
+}
+
+// docTree is what the docs agent knows about the head revision: the lines of
+// documentation that may reference symbols, and the symbols still in use.
+type docTree struct {
+	docLines     []docLine
+	codeTokens   map[string]bool
+	declared     map[string]bool
+	binaries     []string
+	hasChangelog bool
+}
+
+var (
+	goIdentToken = regexp.MustCompile(`[A-Za-z_]\w*`)
+	cobraHelp    = regexp.MustCompile(`^\s*(Short|Long|Example):`)
+)
+
+// scanDocTree reads Markdown files, godoc comments and cobra help text. Only
+// the Unreleased section of the CHANGELOG is documentation of the current
+// state; released entries legitimately name old symbols.
+func scanDocTree(repoPath, ref string, files []string) (*docTree, error) {
+	tree := &docTree{codeTokens: make(map[string]bool), declared: make(map[string]bool)}
+	for _, file := range files {
+		if isVendoredPath(file) {
+			continue
+		}
+		if parts := strings.Split(file, "/"); len(parts) > 2 && parts[0] == "cmd" {
+			if !containsString(tree.binaries, parts[1]) {
+				tree.binaries = append(tree.binaries, parts[1])
+			}
+		}
+		isMarkdown := strings.HasSuffix(file, ".md")
+		if !isMarkdown && !isDocSource(file) {
+			continue
+		}
+		src, err := readFileAtRevision(repoPath, ref, file)
+		if err != nil {
+			continue
+		}
+
+		if isMarkdown {
+			inSection := file != changelogFile
+			for i, l := range strings.Split(string(src), "\n") {
+				if file == changelogFile {
+					tree.hasChangelog = true
+					if strings.HasPrefix(l, "## ") {
+						inSection = unreleasedHeading.MatchString(l)
+					}
+				}
+				if inSection {
+					tree.docLines = append(tree.docLines, docLine{file, i + 1, l})
+				}
+			}
+			continue
+		}
+
+		for _, s := range goDocSymbols(file, src) {
+			if s.Kind != "identifier" {
+				tree.declared[s.Kind+" "+s.Name] = true
+			}
+		}
+		for i, l := range strings.Split(string(src), "\n") {
+			trimmed := strings.TrimSpace(l)
+			switch {
+			case strings.HasPrefix(trimmed, "//go:"):
+			case strings.HasPrefix(trimmed, "//") || cobraHelp.MatchString(l):
+				tree.docLines = append(tree.docLines, docLine{file, i + 1, l})
+			default:
+				for _, t := range goIdentToken.FindAllString(l, -1) {
+					tree.codeTokens[t] = true
+				}
+			}
+		}
+	}
+	return tree, nil
+}
+
+// defines reports whether the head revision still declares or uses s, e.g.
+// because it moved to another file or another command has the same flag.
+func (t *docTree) defines(s DocSymbol) bool {
+	if s.Kind == "identifier" {
+		return t.codeTokens[s.Name]
+	}
+	return t.declared[s.Kind+" "+s.Name]
+}
+
+// referencePattern matches the ways documentation refers to s.
+func (t *docTree) referencePattern(s DocSymbol) *regexp.Regexp {
+	name := regexp.QuoteMeta(s.Name)
+	switch s.Kind {
+	case "flag":
+		return regexp.MustCompile(`--` + name + `\b`)
+	case "command":
+		bins := "[\\w-]+"
+		if len(t.binaries) > 0 {
+			bins = strings.Join(t.binaries, "|")
+		}
+		return regexp.MustCompile(`\b(?:` + bins + `)(?: [a-z][\w-]*)? ` + name + `\b`)
+	case "config-key":
+		return regexp.MustCompile("`" + name + "`|\\b" + name + ":|\\." + name + `\b`)
+	}
+	return regexp.MustCompile(`\b` + name + `\b`)
+}
@@ -0,0 +1,368 @@
+package agent
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v3"
+)
+
+const driftSystemPrompt = "You are a software architect reviewing a change for architectural drift. Be concise and concrete."
+
+type DriftAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewDriftAgent(cfg *config.Config) Agent {
+	return &DriftAgent{
+		BaseAgent: BaseAgent{
+			id:          "drift",
+			description: "Checks changes against declared architecture rules",
+			model:       cfg.Models.Primary,
+		},
+		cfg: cfg,
+	}
+}
+
+func (*DriftAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"Go"}, Prompts: map[string]string{"system": driftSystemPrompt}}
+}
+
+// DefaultThresholds falls back to thresholds.drift_score.
+func (a *DriftAgent) DefaultThresholds() map[string]float64 {
+	return map[string]float64{"drift_score": float64(a.cfg.Thresholds.DriftScore)}
+}
+
+// ArchitectureRules corresponds to the structure of .verifier/architecture.yaml.
+// Package patterns are module-relative ("internal/cli") or full import paths,
+// and may end in "/..." to match a whole subtree.
+type ArchitectureRules struct {
+	// Layers are ordered from top to bottom. A package may import packages in
+	// its own or lower layers, but never from a layer above it.
+	Layers []ArchitectureLayer `yaml:"layers"`
+	// Dependencies restrict which module packages a matching package may import.
+	Dependencies []DependencyRule `yaml:"dependencies"`
+	Forbidden    []ForbiddenRule  `yaml:"forbidden"`
+	Weights      DriftWeights     `yaml:"weights"`
+	// LLMCommentary asks the primary model to comment on the intent behind
+	// any violations found.
+	LLMCommentary bool `yaml:"llm_commentary"`
+}
+
+type ArchitectureLayer struct {
+	Name     string   `yaml:"name"`
+	Packages []string `yaml:"packages"`
+}
+
+type DependencyRule struct {
+	From  string   `yaml:"from"`
+	Allow []string `yaml:"allow"`
+}
+
+type ForbiddenRule struct {
+	From    string   `yaml:"from"` // empty matches every package
+	Imports []string `yaml:"imports"`
+	Reason  string   `yaml:"reason"`
+}
+
+type DriftWeights struct {
+	Forbidden  int `yaml:"forbidden"`
+	Layering   int `yaml:"layering"`
+	Dependency int `yaml:"dependency"`
+}
+
+type DriftAnalysis struct {
+	Score      int       `json:"score"`
+	Threshold  int       `json:"threshold"`
+	Findings   []Finding `json:"-"`
+	Commentary string    `json:"commentary,omitempty"`
+}
+
+func (a *DriftAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	rules, err := loadArchitectureRules(filepath.Join(repoPath, ".verifier", "architecture.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No .verifier/architecture.yaml found"})
+		return &res, nil
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range ctx.Files {
+		if strings.HasSuffix(file, ".go") {
+			dirs["./"+filepath.ToSlash(filepath.Dir(file))] = true
+		}
+	}
+	if len(dirs) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No Go files changed"})
+		return &res, nil
+	}
+	var patterns []string
+	for dir := range dirs {
+		patterns = append(patterns, dir)
+	}
+	sort.Strings(patterns)
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedModule,
+		Dir:  repoPath,
+	}, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	changed, err := patch.ChangedLines(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	changedFiles := make(map[string]bool)
+	for _, file := range ctx.Files {
+		changedFiles[filepath.Clean(file)] = true
+	}
+
+	analysis := DriftAnalysis{Threshold: int(agentThreshold(a.cfg, a.ID(), "drift_score", float64(a.cfg.Thresholds.DriftScore)))}
+	for _, pkg := range pkgs {
+		if pkg.Module == nil {
+			continue
+		}
+		modulePath := pkg.Module.Path
+		from := relativePackage(pkg.PkgPath, modulePath)
+
+		for _, absFile := range pkg.GoFiles {
+			file, err := filepath.Rel(repoPath, absFile)
+			if err != nil || !changedFiles[file] {
+				continue
+			}
+			imports, err := fileImports(absFile)
+			if err != nil {
+				continue
+			}
+			added := make(map[int]bool)
+			for _, n := range changed[filepath.ToSlash(file)] {
+				added[n] = true
+			}
+
+			for _, imp := range imports {
+				inModule := imp.path == modulePath || strings.HasPrefix(imp.path, modulePath+"/")
+				for _, v := range rules.check(from, relativePackage(imp.path, modulePath), inModule) {
+					v.File = filepath.ToSlash(file)
+					v.Line = imp.line
+					if added[imp.line] {
+						analysis.Score += v.weight
+					} else {
+						// Pre-existing drift is reported but does not count
+						// towards the score of this change.
+						v.Severity = "info"
+						v.Message += " (pre-existing)"
+					}
+					analysis.Findings = append(analysis.Findings, v.Finding)
+				}
+			}
+		}
+	}
+	if analysis.Score > 100 {
+		analysis.Score = 100
+	}
+
+	tokensUsed := 0
+	if rules.LLMCommentary && analysis.Score > 0 {
+		analysis.Commentary, tokensUsed = a.commentary(ctx, analysis.Findings)
+	}
+
+	severity := "info"
+	if analysis.Score > analysis.Threshold {
+		severity = "blocking"
+	} else if analysis.Score > 0 {
+		severity = "warning"
+	}
+
+	res := a.CreateResult(AgentResult{
+		Score:      analysis.Score,
+		Data:       analysis,
+		Findings:   analysis.Findings,
+		Severity:   severity,
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+	})
+	return &res, nil
+}
+
+// commentary asks the model to explain the violations. It is best effort: a
+// provider failure leaves the commentary empty.
+func (a *DriftAgent) commentary(ctx AgentContext, findings []Finding) (string, int) {
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return "", 0
+	}
+
+	var sb strings.Builder
+	for _, f := range findings {
+		fmt.Fprintf(&sb, "- %s:%d %s\n", f.File, f.Line, f.Message)
+	}
+	prompt := fmt.Sprintf("The following code diff violates the project's declared architecture rules.\n\nViolations:\n%s\nDiff:\n%s\n\nBriefly explain what the author was likely trying to achieve and how to do it without breaking the architecture.", sb.String(), ctx.Diff)
+	response, err := p.Complete(prompt, driftSystemPrompt, false)
+	if err != nil {
+		return "", 0
+	}
+	return response, len(prompt)/4 + len(response)/4
+}
+
+func loadArchitectureRules(path string) (*ArchitectureRules, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules := ArchitectureRules{Weights: DriftWeights{Forbidden: 10, Layering: 5, Dependency: 5}}
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &rules, nil
+}
+
+type driftViolation struct {
+	Finding
+	weight int
+}
+
+// check returns the rule violations for an import of to by from. Both are
+// module-relative when inside the module.
+func (r *ArchitectureRules) check(from, to string, inModule bool) []driftViolation {
+	var violations []driftViolation
+
+	for _, rule := range r.Forbidden {
+		if rule.From != "" && !matchPackage(rule.From, from) {
+			continue
+		}
+		for _, pattern := range rule.Imports {
+			if matchPackage(pattern, to) {
+				msg := fmt.Sprintf("%s must not import %s", from, to)
+				if rule.Reason != "" {
+					msg += ": " + rule.Reason
+				}
+				violations = append(violations, driftViolation{
+					Finding: Finding{Rule: "forbidden-import", Severity: "warning", Message: msg},
+					weight:  r.Weights.Forbidden,
+				})
+				break
+			}
+		}
+	}
+
+	// Layering and dependency rules only apply within the module.
+	if !inModule {
+		return violations
+	}
+
+	fromLayer, toLayer := r.layerOf(from), r.layerOf(to)
+	if fromLayer >= 0 && toLayer >= 0 && toLayer < fromLayer {
+		violations = append(violations, driftViolation{
+			Finding: Finding{
+				Rule:     "layer-violation",
+				Severity: "warning",
+				Message:  fmt.Sprintf("%s (layer %s) must not import %s (layer %s)", from, r.Layers[fromLayer].Name, to, r.Layers[toLayer].Name),
+			},
+			weight: r.Weights.Layering,
+		})
+	}
+
+	for _, rule := range r.Dependencies {
+		if !matchPackage(rule.From, from) {
+			continue
+		}
+		allowed := false
+		for _, pattern := range rule.Allow {
+			if matchPackage(pattern, to) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, driftViolation{
+				Finding: Finding{
+					Rule:     "disallowed-dependency",
+					Severity: "warning",
+					Message:  fmt.Sprintf("%s is not allowed to depend on %s", from, to),
+				},
+				weight: r.Weights.Dependency,
+			})
+		}
+	}
+	return violations
+}
+
+// layerOf returns the index of the first layer containing pkg, or -1.
+func (r *ArchitectureRules) layerOf(pkg string) int {
+	for i, layer := range r.Layers {
+		for _, pattern := range layer.Packages {
+			if matchPackage(pattern, pkg) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// matchPackage reports whether pkg matches pattern. "x/..." matches x and
+// every package below it; other patterns use path.Match syntax.
+func matchPackage(pattern, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	ok, _ := path.Match(pattern, pkg)
+	return ok
+}
+
+// relativePackage strips the module path from import paths inside the module.
+func relativePackage(importPath, modulePath string) string {
+	if importPath == modulePath {
+		return "."
+	}
+	if rel, ok := strings.CutPrefix(importPath, modulePath+"/"); ok {
+		return rel
+	}
+	return importPath
+}
+
+type fileImport struct {
+	path string
+	line int
+}
+
+func fileImports(path string) ([]fileImport, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	var imports []fileImport
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		imports = append(imports, fileImport{path: p, line: fset.Position(spec.Pos()).Line})
+	}
+	return imports, nil
+}
@@ -0,0 +1,476 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+	"gopkg.in/yaml.v3"
+)
+
+const iacSystemPrompt = "You are a cloud security engineer reviewing infrastructure-as-code. Be concise and concrete."
+
+type IaCAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewIaCAgent(cfg *config.Config) Agent {
+	return &IaCAgent{
+		BaseAgent: BaseAgent{
+			id:          "iac",
+			description: "Checks Dockerfiles, Kubernetes manifests, Terraform and GitHub workflows",
+			model:       cfg.Models.Primary,
+		},
+		cfg: cfg,
+	}
+}
+
+func (*IaCAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"Dockerfile", "GitHub Actions", "Kubernetes", "Terraform"}, Prompts: map[string]string{"system": iacSystemPrompt}}
+}
+
+// IaCOptions configures the iac agent under agents.iac.options.
+type IaCOptions struct {
+	// Explain asks the primary model to explain the risk of the findings.
+	Explain bool `mapstructure:"explain" yaml:"explain"`
+}
+
+func (*IaCAgent) DefaultOptions() any {
+	return IaCOptions{}
+}
+
+type IaCReport struct {
+	Files       []string  `json:"files"`
+	Findings    []Finding `json:"-"`
+	Explanation string    `json:"explanation,omitempty"`
+}
+
+// iacChecker returns the rule violations in one infrastructure file.
+type iacChecker func(file string, content []byte) []Finding
+
+func (a *IaCAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(IaCOptions))
+	if err != nil {
+		return nil, err
+	}
+	changed, err := patch.ChangedLines(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	var files []string
+	for file := range changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var report IaCReport
+	for _, file := range files {
+		content, err := readChangedFile(repoPath, ctx.HeadRef, file)
+		if err != nil {
+			continue
+		}
+		check := iacCheckerFor(file, content)
+		if check == nil {
+			continue
+		}
+		report.Files = append(report.Files, file)
+
+		added := make(map[int]bool)
+		for _, n := range changed[file] {
+			added[n] = true
+		}
+		for _, f := range check(file, content) {
+			if !added[f.Line] {
+				f.Severity = "info"
+				f.Message += " (pre-existing)"
+			}
+			report.Findings = append(report.Findings, f)
+		}
+	}
+
+	if len(report.Files) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No infrastructure files changed"})
+		return &res, nil
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	tokensUsed := 0
+	if opts.Explain && severity != "info" {
+		report.Explanation, tokensUsed = a.explain(ctx, report.Findings)
+	}
+
+	res := a.CreateResult(AgentResult{
+		Data:       report,
+		Findings:   report.Findings,
+		Severity:   severity,
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+	})
+	return &res, nil
+}
+
+// FindingsSeverity is the severity of the worst finding.
+func (*IaCAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		if f.Severity == "warning" {
+			severity = "warning"
+		}
+	}
+	return severity
+}
+
+// explain asks the model to describe the risk of the new findings. It is best
+// effort: a provider failure leaves the explanation empty.
+func (a *IaCAgent) explain(ctx AgentContext, findings []Finding) (string, int) {
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return "", 0
+	}
+
+	var sb strings.Builder
+	for _, f := range findings {
+		if f.Severity != "info" {
+			fmt.Fprintf(&sb, "- %s:%d [%s] %s\n", f.File, f.Line, f.Rule, f.Message)
+		}
+	}
+	prompt := fmt.Sprintf("The following infrastructure configuration change was flagged by static rules.\n\nFindings:\n%s\nDiff:\n%s\n\nFor each finding, briefly explain the concrete risk in this deployment and how to fix it.", sb.String(), ctx.Diff)
+	response, err := p.Complete(prompt, iacSystemPrompt, false)
+	if err != nil {
+		return "", 0
+	}
+	return response, len(prompt)/4 + len(response)/4
+}
+
+func iacCheckerFor(file string, content []byte) iacChecker {
+	base := path.Base(file)
+	ext := path.Ext(file)
+	switch {
+	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") || ext == ".dockerfile":
+		return checkDockerfile
+	case ext == ".tf":
+		return checkTerraform
+	case ext == ".yml" || ext == ".yaml":
+		if strings.HasPrefix(file, ".github/workflows/") {
+			return checkWorkflow
+		}
+		if bytes.Contains(content, []byte("apiVersion:")) && bytes.Contains(content, []byte("kind:")) {
+			return checkKubernetes
+		}
+	}
+	return nil
+}
+
+// secretName matches variable and attribute names that conventionally hold
+// credentials.
+var secretName = regexp.MustCompile(`(?i)(^|_)(password|passwd|secret|token|api_?key|access_?key|secret_?key|private_?key)$`)
+
+// isLiteralSecret reports whether name looks like a credential and value is a
+// literal rather than a reference to a variable or secret store.
+func isLiteralSecret(name, value string) bool {
+	value = strings.Trim(value, `"'`)
+	return secretName.MatchString(name) && value != "" &&
+		!strings.Contains(value, "${") && !strings.HasPrefix(value, "$")
+}
+
+// unpinnedImage reports whether an image reference floats: it has neither a
+// digest nor a tag other than "latest".
+func unpinnedImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i < 0 || name[i+1:] == "latest"
+}
+
+func isRootUser(user string) bool {
+	user, _, _ = strings.Cut(user, ":")
+	return user == "" || user == "root" || user == "0"
+}
+
+// checkDockerfile flags floating base images, final stages running as root
+// and credentials baked into ENV or ARG.
+func checkDockerfile(file string, content []byte) []Finding {
+	var findings []Finding
+	add := func(line int, rule, severity, msg string) {
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Message: msg, File: file, Line: line})
+	}
+
+	stages := make(map[string]bool)
+	var user string
+	var userLine, fromLine int
+
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		text := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(text, "\\") && i+1 < len(lines) {
+			i++
+			text = strings.TrimSuffix(text, "\\") + " " + strings.TrimSpace(lines[i])
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		args := fields[1:]
+
+		switch strings.ToUpper(fields[0]) {
+		case "FROM":
+			for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+				args = args[1:]
+			}
+			if len(args) == 0 {
+				continue
+			}
+			image := args[0]
+			if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+				stages[strings.ToLower(args[2])] = true
+			}
+			if image != "scratch" && !stages[strings.ToLower(image)] && !strings.Contains(image, "$") && unpinnedImage(image) {
+				add(start, "iac/latest-tag", "warning", fmt.Sprintf("Base image %s is not pinned to a tag or digest", image))
+			}
+			user, userLine, fromLine = "", 0, start
+		case "USER":
+			if len(args) > 0 {
+				user, userLine = args[0], start
+			}
+		case "ENV", "ARG":
+			for name, value := range dockerfileAssignments(args) {
+				if isLiteralSecret(name, value) {
+					add(start, "iac/secret-env", "blocking", fmt.Sprintf("%s %s sets a credential in the image", strings.ToUpper(fields[0]), name))
+				}
+			}
+		}
+	}
+
+	if fromLine > 0 && isRootUser(user) {
+		if userLine > 0 {
+			add(userLine, "iac/root-user", "warning", "Container runs as root")
+		} else {
+			add(fromLine, "iac/root-user", "warning", "Final stage has no USER instruction, so the container runs as root")
+		}
+	}
+	return findings
+}
+
+// dockerfileAssignments parses "KEY=value ..." or the legacy "KEY value" form.
+func dockerfileAssignments(args []string) map[string]string {
+	vars := make(map[string]string)
+	if len(args) == 0 {
+		return vars
+	}
+	if !strings.Contains(args[0], "=") {
+		vars[args[0]] = strings.Join(args[1:], " ")
+		return vars
+	}
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		vars[name] = value
+	}
+	return vars
+}
+
+// yamlDocuments decodes every document in a YAML stream. Files that do not
+// parse, such as Helm templates, yield no documents.
+func yamlDocuments(content []byte) []*yaml.Node {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil
+			}
+			return docs
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+// walkMappings calls visit for every key/value pair of every mapping below n.
+func walkMappings(n *yaml.Node, visit func(key, value *yaml.Node)) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			visit(n.Content[i], n.Content[i+1])
+		}
+	}
+	for _, child := range n.Content {
+		walkMappings(child, visit)
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// checkKubernetes flags privileged or root security contexts, floating
+// container images and literal credentials in container env.
+func checkKubernetes(file string, content []byte) []Finding {
+	var findings []Finding
+	add := func(line int, rule, severity, msg string) {
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Message: msg, File: file, Line: line})
+	}
+
+	for _, doc := range yamlDocuments(content) {
+		walkMappings(doc, func(key, value *yaml.Node) {
+			switch key.Value {
+			case "securityContext":
+				if v := mappingValue(value, "privileged"); v != nil && v.Value == "true" {
+					add(v.Line, "iac/privileged", "blocking", "Container runs privileged")
+				}
+				if v := mappingValue(value, "runAsUser"); v != nil && v.Value == "0" {
+					add(v.Line, "iac/root-user", "warning", "Security context runs as root (runAsUser: 0)")
+				}
+				if v := mappingValue(value, "runAsNonRoot"); v != nil && v.Value == "false" {
+					add(v.Line, "iac/root-user", "warning", "Security context allows running as root (runAsNonRoot: false)")
+				}
+			case "containers", "initContainers", "ephemeralContainers":
+				if value.Kind != yaml.SequenceNode {
+					return
+				}
+				for _, c := range value.Content {
+					if image := mappingValue(c, "image"); image != nil && unpinnedImage(image.Value) {
+						add(image.Line, "iac/latest-tag", "warning", fmt.Sprintf("Image %s is not pinned to a tag or digest", image.Value))
+					}
+					env := mappingValue(c, "env")
+					if env == nil || env.Kind != yaml.SequenceNode {
+						continue
+					}
+					for _, e := range env.Content {
+						name, v := mappingValue(e, "name"), mappingValue(e, "value")
+						if name != nil && v != nil && isLiteralSecret(name.Value, v.Value) {
+							add(v.Line, "iac/secret-env", "blocking", fmt.Sprintf("Env var %s holds a literal credential; use a Secret with valueFrom", name.Value))
+						}
+					}
+				}
+			}
+		})
+	}
+	return findings
+}
+
+var fullCommitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// checkWorkflow flags actions not pinned to a commit SHA and literal
+// credentials in env blocks.
+func checkWorkflow(file string, content []byte) []Finding {
+	var findings []Finding
+	add := func(line int, rule, severity, msg string) {
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Message: msg, File: file, Line: line})
+	}
+
+	for _, doc := range yamlDocuments(content) {
+		walkMappings(doc, func(key, value *yaml.Node) {
+			switch key.Value {
+			case "uses":
+				action := value.Value
+				if strings.HasPrefix(action, "./") || strings.HasPrefix(action, "docker://") {
+					return
+				}
+				if _, ref, _ := strings.Cut(action, "@"); !fullCommitSHA.MatchString(ref) {
+					add(value.Line, "iac/unpinned-action", "warning", fmt.Sprintf("Action %s is not pinned to a full commit SHA", action))
+				}
+			case "env":
+				if value.Kind != yaml.MappingNode {
+					return
+				}
+				for i := 0; i+1 < len(value.Content); i += 2 {
+					name, v := value.Content[i], value.Content[i+1]
+					if !strings.Contains(v.Value, "${{") && isLiteralSecret(name.Value, v.Value) {
+						add(v.Line, "iac/secret-env", "blocking", fmt.Sprintf("Env var %s holds a literal credential; use ${{ secrets.* }}", name.Value))
+					}
+				}
+			}
+		})
+	}
+	return findings
+}
+
+var (
+	tfOpenCIDR   = regexp.MustCompile(`^\s*(cidr_blocks|ipv6_cidr_blocks|cidr_ipv4|cidr_ipv6|source_ranges)\s*=.*"(0\.0\.0\.0/0|::/0)"`)
+	tfAttribute  = regexp.MustCompile(`^\s*([A-Za-z0-9_]+)\s*=\s*"([^"]*)"\s*$`)
+	tfEgressRule = regexp.MustCompile(`^\s*type\s*=\s*"egress"`)
+)
+
+// checkTerraform flags ingress rules open to the internet and literal
+// credentials. It scans blocks by brace depth rather than parsing HCL.
+func checkTerraform(file string, content []byte) []Finding {
+	var findings, pending []Finding
+	var headers []string
+	egress := false
+
+	for i, line := range strings.Split(string(content), "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+
+		if m := tfAttribute.FindStringSubmatch(line); m != nil && isLiteralSecret(m[1], m[2]) {
+			findings = append(findings, Finding{Rule: "iac/secret-env", Severity: "blocking", Message: fmt.Sprintf("Attribute %s holds a literal credential", m[1]), File: file, Line: i + 1})
+		}
+		if tfEgressRule.MatchString(line) {
+			egress = true
+		}
+		if tfOpenCIDR.MatchString(line) && !containsWord(headers, "egress") {
+			pending = append(pending, Finding{Rule: "iac/open-security-group", Severity: "blocking", Message: "Ingress is open to the whole internet", File: file, Line: i + 1})
+		}
+
+		opens, closes := strings.Count(line, "{"), strings.Count(line, "}")
+		for ; opens > closes; opens-- {
+			headers = append(headers, strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "{")))
+		}
+		for ; closes > opens && len(headers) > 0; closes-- {
+			headers = headers[:len(headers)-1]
+		}
+		if len(headers) == 0 {
+			// End of a top-level block: standalone rules declared as egress
+			// are allowed to reach anywhere.
+			if !egress {
+				findings = append(findings, pending...)
+			}
+			pending, egress = nil, false
+		}
+	}
+	return append(findings, pending...)
+}
+
+func containsWord(headers []string, word string) bool {
+	for _, h := range headers {
+		if strings.Contains(h, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// validateJSONSchema checks a decoded JSON value against the subset of JSON
+// Schema that prompt agents use: type, properties, required,
+// additionalProperties, items, enum, minimum and maximum. It returns one
+// message per violation; at is the path of value, e.g. "$.findings[0]".
+func validateJSONSchema(schema map[string]any, value any, at string) []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []any:
+			for _, v := range t {
+				types = append(types, fmt.Sprint(v))
+			}
+		}
+		matched := false
+		for _, t := range types {
+			if jsonTypeMatches(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %v, got %s", t, jsonTypeName(value))
+			return problems
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v is not one of %v", value, enum)
+		}
+	}
+
+	if n, ok := toFloat(value); ok {
+		if min, ok := toFloat(schema["minimum"]); ok && n < min {
+			fail("%v is less than the minimum %v", n, min)
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && n > max {
+			fail("%v is greater than the maximum %v", n, max)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				if _, ok := v[fmt.Sprint(r)]; !ok {
+					fail("missing required property %q", r)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if sub, ok := props[k].(map[string]any); ok {
+				problems = append(problems, validateJSONSchema(sub, v[k], at+"."+k)...)
+			} else if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				fail("unexpected property %q", k)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+func jsonTypeMatches(t string, v any) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonEqual compares a schema value, which may come from YAML with Go int
+// types, to a decoded JSON value.
+func jsonEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,523 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"golang.org/x/mod/module"
+)
+
+type LicenseAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewLicenseAgent(cfg *config.Config) Agent {
+	return &LicenseAgent{
+		BaseAgent: BaseAgent{id: "license", description: "Checks license headers, copied license text and dependency licenses", model: "none"},
+		cfg:       cfg,
+	}
+}
+
+// LicenseOptions configures the license agent under agents.license.options.
+type LicenseOptions struct {
+	// Header is the text new source files must start with, without comment
+	// markers. "{year}" matches any four-digit year. Empty disables the check.
+	Header string `mapstructure:"header" yaml:"header"`
+	// Project is the SPDX identifier of the project license, detected from
+	// the LICENSE file when empty.
+	Project string `mapstructure:"project" yaml:"project"`
+	// Allow and Deny list SPDX identifiers. A non-empty Allow list rejects
+	// every license it does not name.
+	Allow []string `mapstructure:"allow" yaml:"allow"`
+	Deny  []string `mapstructure:"deny" yaml:"deny"`
+}
+
+func (*LicenseAgent) DefaultOptions() any {
+	return LicenseOptions{}
+}
+
+type DependencyLicense struct {
+	Dependency
+	Manifest string `json:"manifest"`
+	License  string `json:"license,omitempty"`
+	// Source is the file the license was read from.
+	Source string `json:"source,omitempty"`
+}
+
+type LicenseReport struct {
+	Project      string              `json:"project,omitempty"`
+	Dependencies []DependencyLicense `json:"dependencies,omitempty"`
+	Findings     []Finding           `json:"-"`
+}
+
+func (a *LicenseAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	if ctx.Diff == "" {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No diff available"})
+		return &res, nil
+	}
+	diffs, err := patch.Parse(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+	rules, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(LicenseOptions))
+	if err != nil {
+		return nil, err
+	}
+
+	report := LicenseReport{Project: rules.Project}
+	if report.Project == "" {
+		report.Project = detectProjectLicense(repoPath, ctx.HeadRef)
+	}
+
+	var patches []AgentArtifact
+	for _, d := range diffs {
+		file := d.NewPath
+		if file == "" || isLicenseFile(file) || isVendoredPath(file) {
+			continue
+		}
+
+		if f := copiedLicense(d, report.Project, rules); f != nil {
+			report.Findings = append(report.Findings, *f)
+		}
+
+		if rules.Header == "" || d.OldPath != "" || commentPrefix(file) == "" {
+			continue
+		}
+		content, err := readChangedFile(repoPath, ctx.HeadRef, file)
+		if err != nil {
+			continue
+		}
+		if isGeneratedFile(content) || hasLicenseHeader(string(content), rules.Header) {
+			continue
+		}
+		report.Findings = append(report.Findings, Finding{Rule: "license/header", Severity: "blocking", Message: "New file is missing the license header", File: file, Line: 1})
+		patches = append(patches, AgentArtifact{Type: "patch", Path: file, Rule: "license/header", Content: headerPatch(file, string(content), rules.Header)})
+	}
+
+	deps, err := a.dependencyLicenses(ctx, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, dep := range deps {
+		report.Dependencies = append(report.Dependencies, dep)
+		if dep.License == "" {
+			report.Findings = append(report.Findings, Finding{Rule: "license/unknown", Severity: "warning", Message: fmt.Sprintf("Could not determine the license of %s@%s offline", dep.Name, dep.Version), File: dep.Manifest})
+		} else if !licensePermitted(dep.License, rules) {
+			report.Findings = append(report.Findings, Finding{Rule: "license/dependency", Severity: "blocking", Message: fmt.Sprintf("%s@%s is licensed under %s, which is not allowed", dep.Name, dep.Version, dep.License), File: dep.Manifest})
+		}
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	res := a.CreateResult(AgentResult{Data: report, Findings: report.Findings, Severity: severity, Artifacts: patches})
+	return &res, nil
+}
+
+// FindingsSeverity blocks on a blocking finding and warns on any other.
+func (*LicenseAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		severity = "warning"
+	}
+	return severity
+}
+
+// copiedLicense looks for license text among the lines added to a file and
+// reports it when it is incompatible with the project license.
+func copiedLicense(d patch.FileDiff, project string, rules LicenseOptions) *Finding {
+	var text strings.Builder
+	firstLine := 0
+	for _, h := range d.Hunks {
+		n := h.NewStart
+		for _, l := range h.Lines {
+			if l.Kind == patch.Added {
+				if firstLine == 0 && licenseSignature.MatchString(l.Text) {
+					firstLine = n
+				}
+				text.WriteString(l.Text)
+				text.WriteByte('\n')
+			}
+			if l.Kind != patch.Removed {
+				n++
+			}
+		}
+	}
+	if firstLine == 0 {
+		return nil
+	}
+
+	id := identifyLicense(text.String())
+	if id == "" || strings.EqualFold(id, project) || licenseCompatible(id, project, rules) {
+		return nil
+	}
+	return &Finding{
+		Rule:     "license/incompatible-text",
+		Severity: "blocking",
+		Message:  fmt.Sprintf("Added code carries %s license text, which is incompatible with the project license", id),
+		File:     d.NewPath,
+		Line:     firstLine,
+	}
+}
+
+// dependencyLicenses resolves the licenses of dependencies added or upgraded
+// in the changed manifests.
+func (a *LicenseAgent) dependencyLicenses(ctx AgentContext, repoPath string) ([]DependencyLicense, error) {
+	var result []DependencyLicense
+	seen := make(map[string]bool)
+	for _, manifest := range ctx.Files {
+		if !isDependencyManifest(manifest) {
+			continue
+		}
+		var before []byte
+		if ctx.BaseRef != "" {
+			var err error
+			before, err = readFileAtRevision(repoPath, ctx.BaseRef, manifest)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read base %s: %w", manifest, err)
+			}
+		}
+		after, err := readChangedFile(repoPath, ctx.HeadRef, manifest)
+		if err != nil {
+			continue // deleted manifest
+		}
+		deps, err := changedDependencies(manifest, before, after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", manifest, err)
+		}
+		sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+
+		root := filepath.Join(repoPath, filepath.Dir(manifest))
+		for _, dep := range deps {
+			key := dep.Ecosystem + " " + dep.Name + "@" + dep.Version
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			dl := DependencyLicense{Dependency: dep, Manifest: manifest}
+			dl.License, dl.Source = findDependencyLicense(root, dep)
+			result = append(result, dl)
+		}
+	}
+	return result, nil
+}
+
+// findDependencyLicense reads a dependency's license from the vendor
+// directory, the Go module cache, node_modules or a local virtualenv.
+func findDependencyLicense(root string, dep Dependency) (string, string) {
+	var dirs []string
+	switch dep.Ecosystem {
+	case "Go":
+		dirs = append(dirs, filepath.Join(root, "vendor", filepath.FromSlash(dep.Name)))
+		if cache := goModCache(); cache != "" {
+			if p, err := module.EscapePath(dep.Name); err == nil {
+				if v, err := module.EscapeVersion(dep.Version); err == nil {
+					dirs = append(dirs, filepath.Join(cache, filepath.FromSlash(p)+"@"+v))
+				}
+			}
+		}
+	case "npm":
+		dir := filepath.Join(root, "node_modules", filepath.FromSlash(dep.Name))
+		if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+			var pkg struct {
+				License json.RawMessage `json:"license"`
+			}
+			if json.Unmarshal(data, &pkg) == nil {
+				var id string
+				var typed struct {
+					Type string `json:"type"`
+				}
+				if json.Unmarshal(pkg.License, &id) == nil && id != "" {
+					return id, filepath.Join(dir, "package.json")
+				}
+				if json.Unmarshal(pkg.License, &typed) == nil && typed.Type != "" {
+					return typed.Type, filepath.Join(dir, "package.json")
+				}
+			}
+		}
+		dirs = append(dirs, dir)
+	case "PyPI":
+		name := strings.ReplaceAll(dep.Name, "-", "_")
+		for _, venv := range []string{".venv", "venv"} {
+			matches, _ := filepath.Glob(filepath.Join(root, venv, "lib", "python*", "site-packages", "*.dist-info"))
+			for _, m := range matches {
+				if strings.EqualFold(filepath.Base(m), name+"-"+dep.Version+".dist-info") {
+					if id := pythonMetadataLicense(filepath.Join(m, "METADATA")); id != "" {
+						return id, filepath.Join(m, "METADATA")
+					}
+					dirs = append(dirs, m, filepath.Join(m, "licenses"))
+				}
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		for _, pattern := range []string{"LICEN[CS]E*", "COPYING*"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, m := range matches {
+				data, err := os.ReadFile(m)
+				if err != nil {
+					continue
+				}
+				if id := identifyLicense(string(data)); id != "" {
+					return id, m
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+var goModCacheDir *string
+
+func goModCache() string {
+	if goModCacheDir == nil {
+		dir := os.Getenv("GOMODCACHE")
+		if dir == "" {
+			if out, err := exec.Command("go", "env", "GOMODCACHE").Output(); err == nil {
+				dir = strings.TrimSpace(string(out))
+			}
+		}
+		goModCacheDir = &dir
+	}
+	return *goModCacheDir
+}
+
+// pythonMetadataLicense reads the license from a wheel METADATA file.
+func pythonMetadataLicense(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var license string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break // end of headers
+		}
+		if v, ok := strings.CutPrefix(line, "License-Expression: "); ok {
+			return strings.TrimSpace(v)
+		}
+		if v, ok := strings.CutPrefix(line, "Classifier: License :: OSI Approved :: "); ok && license == "" {
+			license = classifierLicenses[strings.TrimSpace(v)]
+		}
+	}
+	return license
+}
+
+var classifierLicenses = map[string]string{
+	"MIT License":                                   "MIT",
+	"BSD License":                                   "BSD-3-Clause",
+	"Apache Software License":                       "Apache-2.0",
+	"ISC License (ISCL)":                            "ISC",
+	"Mozilla Public License 2.0 (MPL 2.0)":          "MPL-2.0",
+	"GNU General Public License v2 (GPLv2)":         "GPL-2.0",
+	"GNU General Public License v3 (GPLv3)":         "GPL-3.0",
+	"GNU Lesser General Public License v3 (LGPLv3)": "LGPL-3.0",
+	"GNU Affero General Public License v3":          "AGPL-3.0",
+}
+
+func detectProjectLicense(repoPath, ref string) string {
+	for _, name := range []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING", "LICENCE"} {
+		if data, err := readFileAtRevision(repoPath, ref, name); err == nil {
+			return identifyLicense(string(data))
+		}
+	}
+	return ""
+}
+
+// licenseSignature matches lines that only appear in license texts and
+// headers.
+var licenseSignature = regexp.MustCompile(`(?i)SPDX-License-Identifier:|GNU (AFFERO |LESSER |LIBRARY )?GENERAL PUBLIC LICENSE|Mozilla Public License|Apache License|Permission is hereby granted, free of charge|Redistribution and use in source and binary forms|Server Side Public License|Business Source License|unencumbered software released into the public domain|Permission to use, copy, modify, and/or distribute this software`)
+
+var spdxIdentifier = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+-]+(?:\s+(?:OR|AND|WITH)\s+[A-Za-z0-9.+-]+)*)`)
+
+// identifyLicense recognises common license texts and SPDX tags. It returns
+// an SPDX identifier, or "" when the text is not recognised.
+func identifyLicense(text string) string {
+	if m := spdxIdentifier.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	upper := strings.ToUpper(strings.Join(strings.Fields(text), " "))
+	has := func(s string) bool { return strings.Contains(upper, strings.ToUpper(s)) }
+	v3 := has("Version 3") || has("version 3 of the License")
+
+	switch {
+	case has("GNU AFFERO GENERAL PUBLIC LICENSE"):
+		return "AGPL-3.0"
+	case has("GNU LESSER GENERAL PUBLIC LICENSE") || has("GNU LIBRARY GENERAL PUBLIC LICENSE"):
+		if v3 {
+			return "LGPL-3.0"
+		}
+		return "LGPL-2.1"
+	case has("GNU GENERAL PUBLIC LICENSE"):
+		if v3 {
+			return "GPL-3.0"
+		}
+		return "GPL-2.0"
+	case has("Server Side Public License"):
+		return "SSPL-1.0"
+	case has("Business Source License"):
+		return "BUSL-1.1"
+	case has("Mozilla Public License"):
+		return "MPL-2.0"
+	case has("Apache License") && has("Version 2.0"):
+		return "Apache-2.0"
+	case has("Permission is hereby granted, free of charge"):
+		return "MIT"
+	case has("Redistribution and use in source and binary forms"):
+		if has("Neither the name") {
+			return "BSD-3-Clause"
+		}
+		return "BSD-2-Clause"
+	case has("Permission to use, copy, modify, and/or distribute this software"):
+		return "ISC"
+	case has("unencumbered software released into the public domain"):
+		return "Unlicense"
+	}
+	return ""
+}
+
+// copyleftLicenses require derived works to be distributed under the same
+// license, so they cannot be mixed into a differently licensed project.
+var copyleftLicenses = []string{"GPL-", "AGPL-", "SSPL-", "BUSL-"}
+
+func isCopyleft(id string) bool {
+	for _, prefix := range copyleftLicenses {
+		if strings.HasPrefix(strings.ToUpper(id), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseCompatible reports whether code under id may be copied into the
+// project. Configured lists take precedence over the copyleft heuristic.
+func licenseCompatible(id, project string, rules LicenseOptions) bool {
+	if len(rules.Allow) > 0 || len(rules.Deny) > 0 {
+		return licensePermitted(id, rules)
+	}
+	return !isCopyleft(id) || strings.EqualFold(id, project)
+}
+
+var spdxOr = regexp.MustCompile(`(?i)\s+OR\s+`)
+
+// licensePermitted checks an SPDX expression against the allow and deny
+// lists. For "A OR B" it is enough that one alternative is permitted.
+func licensePermitted(expr string, rules LicenseOptions) bool {
+	for _, id := range spdxOr.Split(strings.Trim(expr, "()"), -1) {
+		id = strings.TrimSpace(id)
+		if containsFold(rules.Deny, id) {
+			continue
+		}
+		if len(rules.Allow) == 0 || containsFold(rules.Allow, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLicenseFile(file string) bool {
+	base := strings.ToUpper(path.Base(file))
+	return strings.HasPrefix(base, "LICENSE") || strings.HasPrefix(base, "LICENCE") ||
+		strings.HasPrefix(base, "COPYING") || strings.HasPrefix(base, "NOTICE")
+}
+
+func isVendoredPath(file string) bool {
+	return strings.HasPrefix(file, "vendor/") || strings.HasPrefix(file, "third_party/") ||
+		strings.Contains(file, "node_modules/")
+}
+
+// commentPrefix returns the line comment marker used for the license header
+// of a source file, or "" for files that are not checked.
+func commentPrefix(file string) string {
+	switch path.Ext(file) {
+	case ".go", ".js", ".jsx", ".ts", ".tsx", ".java", ".kt", ".scala", ".c", ".h", ".cc", ".cpp", ".hpp", ".cs", ".rs", ".swift", ".proto":
+		return "//"
+	case ".py", ".sh", ".rb", ".pl":
+		return "#"
+	}
+	return ""
+}
+
+var generatedMarker = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$|@generated`)
+
+func isGeneratedFile(content []byte) bool {
+	head := content
+	if len(head) > 2048 {
+		head = head[:2048]
+	}
+	return generatedMarker.Match(head)
+}
+
+// headerScanLines is how far into a file the license header is looked for.
+const headerScanLines = 30
+
+// hasLicenseHeader reports whether the comments at the top of content
+// contain header, ignoring comment markers and whitespace.
+func hasLicenseHeader(content, header string) bool {
+	lines := strings.SplitN(content, "\n", headerScanLines+1)
+	if len(lines) > headerScanLines {
+		lines = lines[:headerScanLines]
+	}
+	var sb strings.Builder
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		for _, marker := range []string{"//", "/*", "*/", "*", "#!", "#", "<!--", "-->"} {
+			l = strings.TrimPrefix(l, marker)
+		}
+		l = strings.TrimSuffix(strings.TrimSpace(l), "*/")
+		sb.WriteString(l)
+		sb.WriteByte(' ')
+	}
+	have := strings.Join(strings.Fields(sb.String()), " ")
+	want := regexp.QuoteMeta(strings.Join(strings.Fields(header), " "))
+	want = strings.ReplaceAll(want, regexp.QuoteMeta("{year}"), `\d{4}`)
+	return regexp.MustCompile(want).MatchString(have)
+}
+
+// headerPatch inserts the configured header, commented for the file's
+// language, at the top of a file or after its shebang line.
+func headerPatch(file, content, header string) string {
+	prefix := commentPrefix(file)
+	var sb strings.Builder
+	for _, l := range strings.Split(strings.TrimSpace(strings.ReplaceAll(header, "{year}", time.Now().Format("2006"))), "\n") {
+		sb.WriteString(strings.TrimRight(prefix+" "+strings.TrimSpace(l), " "))
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+
+	line := 1
+	if strings.HasPrefix(content, "#!") {
+		line = 2
+	}
+	return patch.ReplaceLines(file, content, line, line-1, sb.String())
+}
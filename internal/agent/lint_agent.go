@@ -1,104 +1,374 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
 )
 
 type LintAgent struct {
 	BaseAgent
+	cfg *config.Config
 }
 
-func NewLintAgent() Agent {
+func NewLintAgent(cfg *config.Config) Agent {
 	return &LintAgent{
 		BaseAgent: BaseAgent{id: "lint", description: "Multi-language code linting", model: "none"},
+		cfg:       cfg,
 	}
 }
 
-type LintIssue struct {
-	File     string `json:"file"`
-	Language string `json:"language"`
-	Issues   string `json:"issues"`
-}
-
-func (a *LintAgent) Execute(ctx AgentContext) (*AgentResult, error) {
-	if len(ctx.Files) == 0 {
-		return &AgentResult{AgentID: a.ID(), Status: "skipped", Error: "No files to lint"}, nil
+func (a *LintAgent) Details() AgentDetails {
+	opts, _ := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(LintOptions))
+	var exts []string
+	for _, tool := range opts.enabledTools() {
+		for _, glob := range tool.Globs {
+			if strings.HasPrefix(path.Base(glob), "*.") {
+				exts = append(exts, path.Ext(glob))
+			} else {
+				exts = append(exts, path.Base(glob))
+			}
+		}
 	}
+	return AgentDetails{Languages: extensionLanguages(exts...)}
+}
 
-	var allIssues []LintIssue
-	totalIssues := 0
+// LintTool describes how the lint agent runs one linter. Tools are configured
+// under agents.lint.options.tools, which may also replace the built-in ones.
+type LintTool struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	// Globs select the files the tool checks.
+	Globs []string `mapstructure:"globs" yaml:"globs"`
+	// Command is run from the project root of the files, see Roots. An
+	// argument "{files}" expands to a batch of files to lint, "{dirs}" to the
+	// "./"-prefixed directories holding them, for tools that check whole
+	// packages. "{file}" and "{dir}" run the tool once per file or directory.
+	// A command with none of these runs once per project root.
+	Command []string `mapstructure:"command" yaml:"command"`
+	// Roots are file names, such as go.mod, marking a project root. Files are
+	// linted from the nearest directory above them containing one, or from
+	// the repo root.
+	Roots []string `mapstructure:"roots" yaml:"roots,omitempty"`
+	// Format names the parser for the tool's output, see lintParsers.
+	Format string `mapstructure:"format" yaml:"format"`
+	// Fix is the command that fixes what the tool reports, if it can.
+	Fix []string `mapstructure:"fix" yaml:"fix,omitempty"`
+	// SuccessCodes are the exit codes of a run that completed, whether or not
+	// it found issues. Defaults to 0 and 1.
+	SuccessCodes []int `mapstructure:"success_codes" yaml:"success_codes,omitempty"`
+}
 
-	for _, file := range ctx.Files {
-		var lintOutput string
-		var err error
+var lintTools = []LintTool{
+	{Name: "gofmt", Globs: []string{"*.go"}, Command: []string{"gofmt", "-d", "{files}"}, Format: "gofmt", Fix: []string{"gofmt", "-w", "{files}"}},
+	{Name: "go-vet", Globs: []string{"*.go"}, Command: []string{"go", "vet", "-json", "{dirs}"}, Roots: []string{"go.mod"}, Format: "go-vet"},
+	{Name: "staticcheck", Globs: []string{"*.go"}, Command: []string{"staticcheck", "-f", "json", "{dirs}"}, Roots: []string{"go.mod"}, Format: "staticcheck"},
+	// golangci-lint runs go vet and staticcheck itself, so it is not enabled
+	// by default.
+	{Name: "golangci-lint", Globs: []string{"*.go"}, Command: []string{"golangci-lint", "run", "--out-format", "json", "{dirs}"}, Roots: []string{"go.mod"}, Format: "golangci-lint", Fix: []string{"golangci-lint", "run", "--fix", "{dirs}"}},
+	{Name: "ruff", Globs: []string{"*.py"}, Command: []string{"ruff", "check", "--output-format", "json", "{files}"}, Format: "ruff", Fix: []string{"ruff", "check", "--fix", "{files}"}},
+	{Name: "eslint", Globs: []string{"*.js", "*.jsx", "*.mjs", "*.cjs", "*.ts", "*.tsx"}, Command: []string{"eslint", "-f", "json", "{files}"}, Roots: []string{"package.json"}, Format: "eslint", Fix: []string{"eslint", "--fix", "{files}"}},
+	{Name: "tsc", Globs: []string{"*.ts", "*.tsx"}, Command: []string{"tsc", "--noEmit", "--pretty", "false"}, Roots: []string{"tsconfig.json"}, Format: "tsc", SuccessCodes: []int{0, 1, 2}},
+	{Name: "shellcheck", Globs: []string{"*.sh", "*.bash"}, Command: []string{"shellcheck", "-f", "json", "{files}"}, Format: "shellcheck"},
+	{Name: "hadolint", Globs: []string{"Dockerfile", "Dockerfile.*", "*.Dockerfile"}, Command: []string{"hadolint", "-f", "json", "{files}"}, Format: "hadolint"},
+	{Name: "yamllint", Globs: []string{"*.yml", "*.yaml"}, Command: []string{"yamllint", "-f", "parsable", "{files}"}, Format: "yamllint", SuccessCodes: []int{0, 1, 2}},
+	{Name: "markdownlint", Globs: []string{"*.md"}, Command: []string{"markdownlint", "--json", "{files}"}, Format: "markdownlint", Fix: []string{"markdownlint", "--fix", "{files}"}},
+}
 
-		ext := filepath.Ext(file)
-		lang := getLanguage(ext)
+// LintOptions configures the lint agent under agents.lint.options.
+type LintOptions struct {
+	// Linters selects which tools run. Tools defined under Tools always run.
+	Linters []string `mapstructure:"linters" yaml:"linters"`
+	// Tools adds linters, or replaces the built-in linter of the same name.
+	Tools []LintTool `mapstructure:"tools" yaml:"tools"`
+	// Jobs is the number of linter processes run at once, the number of CPUs
+	// when 0.
+	Jobs int `mapstructure:"jobs" yaml:"jobs"`
+}
 
-		switch ext {
-		case ".go":
-			lintOutput, err = runCommand("gofmt", "-l", file)
-		case ".py":
-			lintOutput, err = runCommand("ruff", "check", file)
-		// Add cases for other languages like eslint for JS/TS
-		default:
-			continue
+func (o LintOptions) Validate() error {
+	if o.Jobs < 0 {
+		return errors.New("jobs must not be negative")
+	}
+	for i, tool := range o.Tools {
+		switch {
+		case tool.Name == "":
+			return fmt.Errorf("tools[%d]: name is required", i)
+		case len(tool.Globs) == 0:
+			return fmt.Errorf("tools[%d] (%s): globs are required", i, tool.Name)
+		case len(tool.Command) == 0:
+			return fmt.Errorf("tools[%d] (%s): command is required", i, tool.Name)
+		case lintParsers[tool.Format] == nil:
+			return fmt.Errorf("tools[%d] (%s): unknown format %q, use %s", i, tool.Name, tool.Format, strings.Join(lintFormats(), ", "))
 		}
-		
-		if err != nil {
-			// Ruff exits with non-zero status if issues are found, which is not an execution error.
-			if ext == ".py" && lintOutput != "" {
-				// This is expected for ruff
-			} else {
-				fmt.Printf("linter for %s failed: %v, output: %s\n", file, err, lintOutput)
-				continue
+		for _, glob := range tool.Globs {
+			if _, err := globRegexp(glob); err != nil {
+				return fmt.Errorf("tools[%d] (%s): invalid glob %q: %v", i, tool.Name, glob, err)
 			}
 		}
+	}
+	tools := o.tools()
+	for _, l := range o.Linters {
+		if _, ok := tools[l]; !ok {
+			var names []string
+			for name := range tools {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("linters: unknown linter %q, use %s", l, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+func (*LintAgent) DefaultOptions() any {
+	var linters []string
+	for _, tool := range lintTools {
+		if tool.Name != "golangci-lint" {
+			linters = append(linters, tool.Name)
+		}
+	}
+	return LintOptions{Linters: linters}
+}
 
-		if lintOutput != "" {
-			allIssues = append(allIssues, LintIssue{File: file, Language: lang, Issues: lintOutput})
-			totalIssues++
+// tools returns the built-in and configured tools by name.
+func (o LintOptions) tools() map[string]LintTool {
+	tools := make(map[string]LintTool)
+	for _, tool := range lintTools {
+		tools[tool.Name] = tool
+	}
+	for _, tool := range o.Tools {
+		tools[tool.Name] = tool
+	}
+	return tools
+}
+
+// enabledTools returns the tools to run, in the order they are listed.
+func (o LintOptions) enabledTools() []LintTool {
+	tools := o.tools()
+	var enabled []LintTool
+	seen := make(map[string]bool)
+	for _, name := range o.Linters {
+		if tool, ok := tools[name]; ok && !seen[name] {
+			seen[name] = true
+			enabled = append(enabled, tool)
+		}
+	}
+	for _, tool := range o.Tools {
+		if !seen[tool.Name] {
+			seen[tool.Name] = true
+			enabled = append(enabled, tool)
 		}
 	}
+	return enabled
+}
+
+// DefaultThresholds: the result warns when there are more than max_issues
+// findings, 10 unless configured.
+func (*LintAgent) DefaultThresholds() map[string]float64 {
+	return map[string]float64{"max_issues": 10}
+}
+
+// LintToolStatus records how a linter run went: "ok", "failed", or
+// "unavailable" when the tool is not installed.
+type LintToolStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LintReport is the data of a lint result.
+type LintReport struct {
+	FilesChecked int              `json:"files_checked"`
+	TotalIssues  int              `json:"total_issues"`
+	Tools        []LintToolStatus `json:"tools"`
+	Findings     []Finding        `json:"-"`
+}
+
+func (a *LintAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	return a.ExecuteContext(context.Background(), ctx)
+}
+
+func (a *LintAgent) ExecuteContext(runCtx context.Context, ctx AgentContext) (*AgentResult, error) {
+	if len(ctx.Files) == 0 {
+		return &AgentResult{AgentID: a.ID(), Status: "skipped", Error: "No files to lint"}, nil
+	}
+
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(LintOptions))
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Fix {
+		return a.fix(runCtx, ctx, opts)
+	}
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	// Lint the content the diff describes, the staged files or HeadRef, so that
+	// findings and their lines match it, without touching the working tree.
+	headDir, cleanup, err := checkoutChange(runCtx, repoPath, ctx.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	report, patches := lintFiles(runCtx, headDir, ctx.Files, opts)
+	return a.result(ctx, report, patches), nil
+}
+
+// result drops the findings outside the change under ctx.NewCode, writes the
+// lint report artifact and wraps report in a result, so that total_issues,
+// the artifact and the severity only count the findings reported.
+func (a *LintAgent) result(ctx AgentContext, report LintReport, patches []AgentArtifact) *AgentResult {
+	var hidden int
+	report.Findings, hidden = newCodeFindings(report.Findings, ctx, ctx.NewCode)
+	report.TotalIssues = len(report.Findings)
 
 	artifactsDir := filepath.Join(".verifier", "artifacts")
 	os.MkdirAll(artifactsDir, 0755)
 	reportPath := filepath.Join(artifactsDir, "lint-report.json")
 
-	if totalIssues > 0 {
-		reportData, _ := json.MarshalIndent(allIssues, "", "  ")
+	if len(report.Findings) > 0 {
+		reportData, _ := json.MarshalIndent(report.Findings, "", "  ")
 		os.WriteFile(reportPath, reportData, 0644)
+	} else {
+		os.Remove(reportPath)
 	}
-	
+
 	severity := "info"
-	if totalIssues > 10 {
+	if float64(len(report.Findings)) > agentThreshold(a.cfg, a.ID(), "max_issues", a.DefaultThresholds()["max_issues"]) {
 		severity = "warning"
 	}
 
 	result := a.CreateResult(AgentResult{
-		Data: map[string]any{
-			"total_issues":  totalIssues,
-			"files_checked": len(ctx.Files),
-			"issues":        allIssues,
-		},
-		Severity:  severity,
-		Artifacts: []AgentArtifact{{Type: "report", Path: reportPath}},
+		Data:           report,
+		Findings:       report.Findings,
+		Severity:       severity,
+		Artifacts:      append([]AgentArtifact{{Type: "report", Path: reportPath}}, patches...),
+		HiddenFindings: hidden,
 	})
-	return &result, nil
+	return &result
 }
 
-func runCommand(name string, args ...string) (string, error) {
-	// Check if the command exists
-	if _, err := exec.LookPath(name); err != nil {
-		return fmt.Sprintf("%s not found in PATH", name), nil
+// lintFiles runs the enabled tools on the files they match in the checkout
+// at dir.
+func lintFiles(runCtx context.Context, dir string, files []string, opts LintOptions) (LintReport, []AgentArtifact) {
+	report := LintReport{FilesChecked: len(files)}
+	var patches []AgentArtifact
+
+	// Plan every tool's jobs first so that all of them share the worker pool.
+	var jobs []lintJob
+	var statuses []*LintToolStatus
+	changed := make(map[string]map[string]bool)
+	for _, tool := range opts.enabledTools() {
+		matched := tool.match(files)
+		if len(matched) == 0 {
+			continue
+		}
+		changed[tool.Name] = make(map[string]bool)
+		for _, file := range matched {
+			changed[tool.Name][file] = true
+		}
+		status := &LintToolStatus{Name: tool.Name, Status: "ok"}
+		statuses = append(statuses, status)
+		if _, err := exec.LookPath(tool.Command[0]); err != nil {
+			status.Status = "unavailable"
+			continue
+		}
+		jobs = append(jobs, planLintJobs(dir, tool, matched)...)
+	}
+
+	errs := make(map[string][]string)
+	for i, res := range runLintJobs(jobs, opts.workers(), func(j lintJob) lintJobResult { return j.run(runCtx) }) {
+		tool := jobs[i].tool
+		if res.err != nil {
+			errs[tool.Name] = append(errs[tool.Name], res.err.Error())
+			continue
+		}
+		for _, f := range res.findings {
+			// Package-level tools also report on files that did not change.
+			if changed[tool.Name][f.File] {
+				report.Findings = append(report.Findings, f)
+			}
+		}
+		if tool.Format == "gofmt" {
+			patches = append(patches, gofmtPatches(jobs[i], string(res.output))...)
+		}
+	}
+	for _, status := range statuses {
+		if len(errs[status.Name]) > 0 {
+			status.Status = "failed"
+			status.Error = strings.Join(errs[status.Name], "; ")
+		}
+		report.Tools = append(report.Tools, *status)
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		fi, fj := report.Findings[i], report.Findings[j]
+		if fi.File != fj.File {
+			return fi.File < fj.File
+		}
+		return fi.Line < fj.Line
+	})
+	report.TotalIssues = len(report.Findings)
+	return report, patches
+}
+
+// match returns the files the tool checks, with slash separators.
+func (t LintTool) match(files []string) []string {
+	var matched []string
+	for _, file := range files {
+		file = filepath.ToSlash(file)
+		if matchAnyGlob(t.Globs, file) {
+			matched = append(matched, file)
+		}
+	}
+	return matched
+}
+
+func (o LintOptions) workers() int {
+	if o.Jobs == 0 {
+		return runtime.NumCPU()
+	}
+	return o.Jobs
+}
+
+// gofmtPatch rewrites the headers of gofmt -d output, which compares
+// "file.orig" with "file", into a patch that applies to file.
+func gofmtPatch(file, diff string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", file, file, file, file)
+	if i := strings.Index(diff, "\n@@ "); i >= 0 {
+		sb.WriteString(diff[i+1:])
+	}
+	return sb.String()
+}
+
+// gofmtPatches splits the gofmt -d output of a job, one "diff" section per
+// file, into a patch per file.
+func gofmtPatches(job lintJob, output string) []AgentArtifact {
+	var patches []AgentArtifact
+	sections := strings.Split("\n"+output, "\ndiff ")
+	for _, section := range sections[1:] {
+		files, err := patch.Parse(section)
+		if err != nil || len(files) != 1 {
+			continue
+		}
+		file := job.repoPath(files[0].Path())
+		patches = append(patches, AgentArtifact{Type: "patch", Path: file, Rule: gofmtRule, Content: gofmtPatch(file, section)})
 	}
-	cmd := exec.Command(name, args...)
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+	return patches
 }
 
 func getLanguage(ext string) string {
@@ -107,10 +377,18 @@ func getLanguage(ext string) string {
 		return "Go"
 	case ".py":
 		return "Python"
-	case ".js":
+	case ".js", ".jsx", ".mjs", ".cjs":
 		return "JavaScript"
-	case ".ts":
+	case ".ts", ".tsx":
 		return "TypeScript"
+	case ".sh", ".bash":
+		return "Shell"
+	case ".yml", ".yaml":
+		return "YAML"
+	case ".md":
+		return "Markdown"
+	case "Dockerfile", "Dockerfile.*", ".Dockerfile":
+		return "Dockerfile"
 	default:
 		return "Unknown"
 	}
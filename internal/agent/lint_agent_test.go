@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+)
+
+func TestLintResultCountsOnlyNewCode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	a := NewLintAgent(&config.Config{}).(*LintAgent)
+
+	var report LintReport
+	for line := 1; line <= 12; line++ {
+		report.Findings = append(report.Findings, Finding{Rule: "lint/gofmt", Severity: "warning", File: "a.go", Line: line, Message: "x"})
+	}
+	report.TotalIssues = len(report.Findings)
+
+	tests := []struct {
+		mode         string
+		wantIssues   int
+		wantHidden   int
+		wantSeverity string
+	}{
+		{"all", 12, 0, "warning"},
+		{"changed-lines", 1, 11, "info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			ctx := AgentContext{Files: []string{"a.go"}, Diff: newCodeDiff, NewCode: tt.mode}
+			res := a.result(ctx, report, nil)
+
+			data := res.Data.(LintReport)
+			if data.TotalIssues != tt.wantIssues || len(res.Findings) != tt.wantIssues {
+				t.Errorf("total_issues = %d with %d findings, want %d", data.TotalIssues, len(res.Findings), tt.wantIssues)
+			}
+			if res.HiddenFindings != tt.wantHidden {
+				t.Errorf("hidden_findings = %d, want %d", res.HiddenFindings, tt.wantHidden)
+			}
+			if res.Severity != tt.wantSeverity {
+				t.Errorf("severity = %q, want %q", res.Severity, tt.wantSeverity)
+			}
+
+			raw, err := os.ReadFile(filepath.Join(".verifier", "artifacts", "lint-report.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var written []Finding
+			if err := json.Unmarshal(raw, &written); err != nil {
+				t.Fatal(err)
+			}
+			if len(written) != tt.wantIssues {
+				t.Errorf("lint-report.json has %d findings, want %d", len(written), tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestLintStagedLeavesWorktree(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	repo := gitRepo(t, map[string]string{"a.go": "package a\n"})
+	t.Chdir(repo)
+	staged := "package a\n\nfunc A() int {return 1}\n"
+	writeFile(t, repo, "a.go", staged)
+	gitCmd(t, repo, "add", "a.go")
+	// The working tree fixes the formatting without staging it.
+	worktree := "package a\n\nfunc A() int { return 1 }\n"
+	writeFile(t, repo, "a.go", worktree)
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(repo, "a.go"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Agents: map[string]config.AgentSettings{
+		"lint": {Options: map[string]any{"linters": []any{"gofmt"}}},
+	}}
+	ctx := AgentContext{RepoPath: repo, Files: []string{"a.go"}}
+	res, err := NewLintAgent(cfg).Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Findings) != 1 || res.Findings[0].Rule != "lint/gofmt" {
+		t.Errorf("findings = %+v, want the gofmt issue in the staged content", res.Findings)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "a.go")); string(got) != worktree {
+		t.Errorf("working tree a.go = %q, want it untouched", got)
+	}
+	if info, err := os.Stat(filepath.Join(repo, "a.go")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("working tree a.go was rewritten")
+	}
+	if got := gitCmd(t, repo, "show", ":a.go"); got != staged {
+		t.Errorf("index a.go = %q, want it untouched", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, unstagedPatchPath)); !os.IsNotExist(err) {
+		t.Errorf("unstaged changes were stashed: %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// unstagedPatchPath holds the unstaged changes set aside while lint --fix
+// runs, so they survive if verifier is interrupted.
+var unstagedPatchPath = filepath.Join(".verifier", "lint-fix-unstaged.patch")
+
+// LintFixReport is the data of a lint --fix result. The remaining issues are
+// the result's findings.
+type LintFixReport struct {
+	LintReport
+	FixedIssues int       `json:"fixed_issues"`
+	Fixed       []Finding `json:"fixed"`
+	// Restaged lists the staged files the fixers changed, which were added
+	// to the index again.
+	Restaged []string `json:"restaged"`
+	// Unfixed lists partially staged files whose unstaged changes conflicted
+	// with the fixes. Their working tree copy was restored as it was; the
+	// fixes are in the index only.
+	Unfixed []string `json:"unfixed,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// fix runs the fix command of each enabled tool on the staged files and
+// stages the result. Unstaged changes to partially staged files are set aside
+// first so that only staged content is fixed and restaged, and are put back
+// afterwards. When runCtx is cancelled the staged content of files is put
+// back, since an interrupted fixer may leave them half written.
+func (a *LintAgent) fix(runCtx context.Context, ctx AgentContext, opts LintOptions) (*AgentResult, error) {
+	if ctx.HeadRef != "" {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "--fix only works on staged changes"})
+		return &res, nil
+	}
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	var files []string
+	for _, file := range ctx.Files {
+		if _, err := os.Stat(filepath.Join(repoPath, file)); err == nil {
+			files = append(files, filepath.ToSlash(file))
+		}
+	}
+	if len(files) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No staged files to fix"})
+		return &res, nil
+	}
+
+	stash, err := stashUnstaged(repoPath, files)
+	if err != nil {
+		return nil, err
+	}
+
+	before, _ := lintFiles(runCtx, repoPath, files, opts)
+	others, err := snapshotUnstaged(repoPath, files)
+	if err != nil {
+		stash.restore(repoPath)
+		return nil, err
+	}
+	staged, _ := snapshotFiles(repoPath, files)
+	report := LintFixReport{Errors: runFixers(runCtx, repoPath, files, opts)}
+	// Fixers of whole packages may edit files outside the change; undo that.
+	if err := others.restoreChanged(repoPath, files); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	if runCtx.Err() != nil {
+		for _, file := range files {
+			full := filepath.Join(repoPath, file)
+			_ = os.WriteFile(full, staged[file], fileMode(full))
+		}
+		if _, err := stash.restore(repoPath); err != nil {
+			return nil, err
+		}
+		return nil, runCtx.Err()
+	}
+	after, patches := lintFiles(runCtx, repoPath, files, opts)
+
+	fixed, _ := snapshotFiles(repoPath, files)
+	for _, file := range files {
+		if !bytes.Equal(staged[file], fixed[file]) {
+			report.Restaged = append(report.Restaged, file)
+		}
+	}
+	if len(report.Restaged) > 0 {
+		if _, err := runGit(repoPath, append([]string{"add", "--"}, report.Restaged...)...); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+	report.Unfixed, err = stash.restore(repoPath)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	// Like the remaining issues, the fixed ones only count those in the
+	// change under ctx.NewCode.
+	res := a.result(ctx, after, patches)
+	report.LintReport = res.Data.(LintReport)
+	changed, _ := newCodeFindings(before.Findings, ctx, ctx.NewCode)
+	report.Fixed = fixedFindings(a.CreateResult(AgentResult{Findings: changed}).Findings, res.Findings)
+	report.FixedIssues = len(report.Fixed)
+	res.Data = report
+	return res, nil
+}
+
+// fixedFindings returns the findings of before that are gone in after.
+// Findings are matched by fingerprint, counting duplicates: the hunks gofmt
+// reports in one file, for one, all share a fingerprint.
+func fixedFindings(before, after []Finding) []Finding {
+	remaining := make(map[string]int)
+	for _, f := range after {
+		remaining[f.Fingerprint]++
+	}
+	var fixed []Finding
+	for _, f := range before {
+		if remaining[f.Fingerprint] > 0 {
+			remaining[f.Fingerprint]--
+			continue
+		}
+		fixed = append(fixed, f)
+	}
+	return fixed
+}
+
+// runFixers runs the fix command of each enabled tool on the files it
+// matches. Tools run one after another since they may edit the same files.
+func runFixers(runCtx context.Context, repoPath string, files []string, opts LintOptions) []string {
+	var errs []string
+	for _, tool := range opts.enabledTools() {
+		matched := tool.match(files)
+		if len(tool.Fix) == 0 || len(matched) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(tool.Fix[0]); err != nil {
+			continue
+		}
+		fixTool := tool
+		fixTool.Command = tool.Fix
+		jobs := planLintJobs(repoPath, fixTool, matched)
+		results := runLintJobs(jobs, opts.workers(), func(j lintJob) lintJobResult {
+			_, _, err := j.exec(runCtx, j.tool.Command)
+			return lintJobResult{err: err}
+		})
+		for _, res := range results {
+			if res.err != nil {
+				errs = append(errs, fmt.Sprintf("%s --fix: %v", tool.Name, res.err))
+			}
+		}
+	}
+	return errs
+}
+
+// unstagedStash holds the unstaged changes of partially staged files.
+type unstagedStash struct {
+	patch []byte
+	// worktree is the working tree content of each stashed file.
+	worktree map[string][]byte
+}
+
+// stashUnstaged sets aside the unstaged changes to files, leaving their
+// staged content in the working tree. The changes are also written to
+// unstagedPatchPath.
+func stashUnstaged(repoPath string, files []string) (*unstagedStash, error) {
+	out, err := runGit(repoPath, append([]string{"diff", "--name-only", "-z", "--"}, files...)...)
+	if err != nil {
+		return nil, err
+	}
+	partial := strings.FieldsFunc(string(out), func(r rune) bool { return r == 0 })
+	stash := &unstagedStash{}
+	if len(partial) == 0 {
+		return stash, nil
+	}
+
+	stash.patch, err = runGit(repoPath, append([]string{"diff", "--binary", "--"}, partial...)...)
+	if err != nil {
+		return nil, err
+	}
+	if stash.worktree, err = snapshotFiles(repoPath, partial); err != nil {
+		return nil, err
+	}
+	backup := filepath.Join(repoPath, unstagedPatchPath)
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(backup, stash.patch, 0644); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(repoPath, append([]string{"checkout", "--"}, partial...)...); err != nil {
+		return nil, err
+	}
+	return stash, nil
+}
+
+// restore applies the stashed changes on top of the fixed files. Files where
+// they conflict with the fixes get their stashed working tree copy back and
+// are returned.
+func (s *unstagedStash) restore(repoPath string) ([]string, error) {
+	if len(s.patch) == 0 {
+		return nil, nil
+	}
+	var files []string
+	for file := range s.worktree {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var conflicted []string
+	for _, file := range files {
+		cmd := exec.Command("git", "-C", repoPath, "apply", "--whitespace=nowarn", "--include="+file, "-")
+		cmd.Stdin = bytes.NewReader(s.patch)
+		if cmd.Run() == nil {
+			continue
+		}
+		full := filepath.Join(repoPath, file)
+		if err := os.WriteFile(full, s.worktree[file], fileMode(full)); err != nil {
+			return conflicted, fmt.Errorf("restore %s: %w; its unstaged changes are in %s", file, err, unstagedPatchPath)
+		}
+		conflicted = append(conflicted, file)
+	}
+	os.Remove(filepath.Join(repoPath, unstagedPatchPath))
+	return conflicted, nil
+}
+
+// fileSnapshot maps paths to their content.
+type fileSnapshot map[string][]byte
+
+// snapshotUnstaged records the working tree content of the files with
+// unstaged changes other than files.
+func snapshotUnstaged(repoPath string, files []string) (fileSnapshot, error) {
+	out, err := runGit(repoPath, "diff", "--name-only", "-z", "--diff-filter=d")
+	if err != nil {
+		return nil, err
+	}
+	var others []string
+	for _, file := range strings.FieldsFunc(string(out), func(r rune) bool { return r == 0 }) {
+		if !containsString(files, file) {
+			others = append(others, file)
+		}
+	}
+	return snapshotFiles(repoPath, others)
+}
+
+// restoreChanged undoes edits to files other than keep: files in the snapshot
+// get their recorded content back, other modified files their index content.
+func (s fileSnapshot) restoreChanged(repoPath string, keep []string) error {
+	out, err := runGit(repoPath, "diff", "--name-only", "-z", "--diff-filter=d")
+	if err != nil {
+		return err
+	}
+	var reset []string
+	for _, file := range strings.FieldsFunc(string(out), func(r rune) bool { return r == 0 }) {
+		if containsString(keep, file) {
+			continue
+		}
+		if content, ok := s[file]; ok {
+			full := filepath.Join(repoPath, file)
+			if err := os.WriteFile(full, content, fileMode(full)); err != nil {
+				return err
+			}
+			continue
+		}
+		reset = append(reset, file)
+	}
+	if len(reset) > 0 {
+		_, err = runGit(repoPath, append([]string{"checkout", "--"}, reset...)...)
+	}
+	return err
+}
+
+func snapshotFiles(repoPath string, files []string) (fileSnapshot, error) {
+	snapshot := make(fileSnapshot)
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(repoPath, file))
+		if err != nil {
+			return nil, err
+		}
+		snapshot[file] = content
+	}
+	return snapshot, nil
+}
+
+func fileMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
+
+// runGit runs a git command in repoPath and returns its stdout.
+func runGit(repoPath string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
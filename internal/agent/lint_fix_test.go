@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+)
+
+func TestFixedFindings(t *testing.T) {
+	f := func(fingerprint string, line int) Finding {
+		return Finding{Fingerprint: fingerprint, Line: line}
+	}
+	tests := []struct {
+		name          string
+		before, after []Finding
+		want          []Finding
+	}{
+		{
+			name:   "all fixed",
+			before: []Finding{f("a", 1), f("b", 2)},
+			want:   []Finding{f("a", 1), f("b", 2)},
+		},
+		{
+			name:   "none fixed, lines moved",
+			before: []Finding{f("a", 1), f("b", 2)},
+			after:  []Finding{f("a", 3), f("b", 4)},
+		},
+		{
+			name:   "one of several hunks with the same fingerprint fixed",
+			before: []Finding{f("gofmt", 1), f("gofmt", 5), f("gofmt", 9)},
+			after:  []Finding{f("gofmt", 1), f("gofmt", 5)},
+			want:   []Finding{f("gofmt", 9)},
+		},
+		{
+			name:   "duplicates all remain",
+			before: []Finding{f("a", 1), f("a", 1)},
+			after:  []Finding{f("a", 1), f("a", 1)},
+		},
+		{
+			name:   "new findings are not fixed ones",
+			before: []Finding{f("a", 1)},
+			after:  []Finding{f("a", 1), f("b", 2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fixedFindings(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fixedFindings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintFixCountsOnlyNewCode(t *testing.T) {
+	old := "package a\n\n// FIXME old\n// TODO old\n"
+	repo := gitRepo(t, map[string]string{"a.go": old})
+	t.Chdir(repo)
+	writeFile(t, repo, "a.go", old+"\n// FIXME new\n// TODO new\n")
+	gitCmd(t, repo, "add", "a.go")
+
+	// fixme reports the FIXMEs its fix command removes, todo the TODOs that
+	// no fixer removes.
+	tool := func(name, word string, fix ...any) map[string]any {
+		return map[string]any{
+			"name":    name,
+			"globs":   []any{"*.go"},
+			"command": []any{"sh", "-c", "grep -n " + word + " a.go | sed 's/^\\([0-9]*\\):.*/a.go:\\1:1: [warning] x (" + name + ")/'"},
+			"format":  "yamllint",
+			"fix":     fix,
+		}
+	}
+	cfg := &config.Config{Agents: map[string]config.AgentSettings{
+		"lint": {Options: map[string]any{
+			"linters": []any{"shellcheck"},
+			"tools":   []any{tool("fixme", "FIXME", "sed", "-i", "s/ FIXME//", "{files}"), tool("todo", "TODO")},
+		}},
+	}}
+	ctx := AgentContext{
+		RepoPath: repo,
+		Files:    []string{"a.go"},
+		Diff:     gitCmd(t, repo, "diff", "--cached"),
+		Fix:      true,
+		NewCode:  "changed-lines",
+	}
+	res, err := NewLintAgent(cfg).Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := res.Data.(LintFixReport)
+	if report.FixedIssues != 1 || len(report.Fixed) != 1 || report.Fixed[0].Line != 6 {
+		t.Errorf("fixed = %d %+v, want the new FIXME only", report.FixedIssues, report.Fixed)
+	}
+	if report.TotalIssues != 1 || len(report.Findings) != 1 || len(res.Findings) != 1 {
+		t.Errorf("total_issues = %d with %d findings in data and %d in the result, want the new TODO only", report.TotalIssues, len(report.Findings), len(res.Findings))
+	}
+	if got := gitCmd(t, repo, "show", ":a.go"); strings.Contains(got, "FIXME") {
+		t.Errorf("staged a.go was not fixed:\n%s", got)
+	}
+}
@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// lintBatchSize caps the number of files or directories passed to one
+// linter process, keeping command lines well below the OS limit.
+const lintBatchSize = 100
+
+// lintJob is one linter process: tool run from root, a directory relative to
+// the checkout dir, on targets relative to root.
+type lintJob struct {
+	tool    LintTool
+	dir     string
+	root    string
+	targets []string
+}
+
+type lintJobResult struct {
+	findings []Finding
+	output   []byte
+	err      error
+}
+
+// planLintJobs groups the files a tool checks by project root and turns each
+// group into batched jobs. Commands with "{files}" or "{dirs}" take a batch
+// of targets, commands with "{file}" or "{dir}" one target each, and other
+// commands run once per root. Files are relative to the checkout dir.
+func planLintJobs(dir string, tool LintTool, files []string) []lintJob {
+	var roots []string
+	targets := make(map[string][]string)
+	for _, file := range files {
+		root := lintRoot(dir, file, tool.Roots)
+		rel := strings.TrimPrefix(file, root+"/")
+		if root == "." {
+			rel = file
+		}
+		target := rel
+		switch tool.placeholder() {
+		case "{dir}", "{dirs}":
+			target = "./" + path.Dir(rel)
+		case "":
+			target = ""
+		}
+		if _, ok := targets[root]; !ok {
+			roots = append(roots, root)
+		}
+		if !containsString(targets[root], target) {
+			targets[root] = append(targets[root], target)
+		}
+	}
+
+	var jobs []lintJob
+	for _, root := range roots {
+		size := lintBatchSize
+		switch tool.placeholder() {
+		case "{file}", "{dir}":
+			size = 1
+		case "":
+			jobs = append(jobs, lintJob{tool: tool, dir: dir, root: root})
+			continue
+		}
+		for batch := range slices.Chunk(targets[root], size) {
+			jobs = append(jobs, lintJob{tool: tool, dir: dir, root: root, targets: batch})
+		}
+	}
+	return jobs
+}
+
+// placeholder returns the target placeholder used by the tool's command, or
+// "" when it has none.
+func (t LintTool) placeholder() string {
+	for _, p := range []string{"{files}", "{file}", "{dirs}", "{dir}"} {
+		for _, arg := range t.Command {
+			if strings.Contains(arg, p) {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// lintRoot returns the nearest directory above file that contains one of the
+// marker files in the checkout at base, or "." for its root.
+func lintRoot(base, file string, markers []string) string {
+	for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(base, dir, marker)); err == nil {
+				return dir
+			}
+		}
+	}
+	return "."
+}
+
+// runLintJobs runs each job with run on at most workers goroutines at a time.
+// The results are in the order of jobs.
+func runLintJobs(jobs []lintJob, workers int, run func(lintJob) lintJobResult) []lintJobResult {
+	results := make([]lintJobResult, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = run(jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// run runs the job's command and parses its output. An exit code outside the
+// tool's success codes is an error. Finding paths are made relative to the
+// repo root.
+func (j lintJob) run(runCtx context.Context) lintJobResult {
+	stdout, stderr, err := j.exec(runCtx, j.tool.Command)
+	if err != nil {
+		return lintJobResult{err: err}
+	}
+	findings, err := lintParsers[j.tool.Format](stdout, stderr)
+	if err != nil {
+		return lintJobResult{err: fmt.Errorf("%s: %w", j.describe(), err)}
+	}
+	for i := range findings {
+		if findings[i].Rule == "" {
+			findings[i].Rule = "lint/" + j.tool.Name
+		}
+		findings[i].File = j.repoPath(findings[i].File)
+	}
+	return lintJobResult{findings: findings, output: stdout}
+}
+
+// exec runs command with the job's targets from its root.
+func (j lintJob) exec(runCtx context.Context, command []string) (stdout, stderr []byte, err error) {
+	var args []string
+	for _, arg := range command {
+		switch arg {
+		case "{files}", "{dirs}":
+			args = append(args, j.targets...)
+			continue
+		}
+		if len(j.targets) > 0 {
+			arg = strings.ReplaceAll(arg, "{file}", j.targets[0])
+			arg = strings.ReplaceAll(arg, "{dir}", j.targets[0])
+		}
+		args = append(args, arg)
+	}
+
+	var out, errOut bytes.Buffer
+	cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+	cmd.Dir = filepath.Join(j.dir, j.root)
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, nil, err
+		}
+		code = exitErr.ExitCode()
+	}
+	successCodes := j.tool.SuccessCodes
+	if len(successCodes) == 0 {
+		successCodes = []int{0, 1}
+	}
+	if !slices.Contains(successCodes, code) {
+		msg, _, _ := strings.Cut(strings.TrimSpace(errOut.String()), "\n")
+		return nil, nil, fmt.Errorf("%s exited with status %d: %s", j.describe(), code, msg)
+	}
+	return out.Bytes(), errOut.Bytes(), nil
+}
+
+// describe names the job's targets for error messages.
+func (j lintJob) describe() string {
+	var targets []string
+	for _, t := range j.targets {
+		targets = append(targets, j.repoPath(t))
+	}
+	switch {
+	case len(targets) == 0:
+		return j.root
+	case len(targets) > 3:
+		return fmt.Sprintf("%s and %d more", strings.Join(targets[:3], ", "), len(targets)-3)
+	}
+	return strings.Join(targets, ", ")
+}
+
+// repoPath makes a path printed by the job's tool relative to the repo root.
+// Relative paths are relative to the job's root, absolute ones are converted
+// against the checkout dir.
+func (j lintJob) repoPath(p string) string {
+	if !filepath.IsAbs(p) {
+		return path.Join(j.root, filepath.ToSlash(p))
+	}
+	base, err := filepath.Abs(j.dir)
+	if err != nil {
+		return p
+	}
+	if rel, err := filepath.Rel(base, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return p
+}
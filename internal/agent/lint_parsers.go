@@ -0,0 +1,547 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/patch"
+)
+
+// lintParser turns the output of a linter into findings.
+type lintParser func(stdout, stderr []byte) ([]Finding, error)
+
+// lintParsers maps LintTool.Format to the parser for that output format.
+var lintParsers = map[string]lintParser{
+	"gofmt":         parseGofmt,
+	"ruff":          parseRuff,
+	"golangci-lint": parseGolangciLint,
+	"eslint":        parseESLint,
+	"go-vet":        parseGoVet,
+	"staticcheck":   parseStaticcheck,
+	"tsc":           parseTsc,
+	"shellcheck":    parseShellcheck,
+	"hadolint":      parseHadolint,
+	"yamllint":      parseYamllint,
+	"markdownlint":  parseMarkdownlint,
+	"text":          parseText,
+}
+
+func lintFormats() []string {
+	var formats []string
+	for format := range lintParsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// lintLevel maps the levels linters such as shellcheck and hadolint assign to
+// a finding severity: errors and warnings warn, the rest is informational.
+func lintLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "error", "warning":
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+const gofmtRule = "lint/gofmt"
+
+// parseGofmt reports one finding per hunk of gofmt -d output, suggesting the
+// formatted text for the lines the hunk covers.
+func parseGofmt(stdout, _ []byte) ([]Finding, error) {
+	files, err := patch.Parse(string(stdout))
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, f := range files {
+		for _, h := range f.Hunks {
+			var formatted []string
+			for _, l := range h.Lines {
+				if l.Kind != patch.Removed {
+					formatted = append(formatted, l.Text)
+				}
+			}
+			findings = append(findings, Finding{
+				Rule:       gofmtRule,
+				Severity:   "warning",
+				Message:    "File is not gofmt-formatted",
+				File:       f.Path(),
+				Line:       h.OldStart,
+				EndLine:    max(h.OldStart, h.OldStart+h.OldLines-1),
+				Tags:       []string{"style"},
+				Suggestion: strings.Join(formatted, "\n"),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseRuff reads ruff check --output-format json.
+func parseRuff(stdout, _ []byte) ([]Finding, error) {
+	var diagnostics []struct {
+		Code     *string `json:"code"`
+		Message  string  `json:"message"`
+		Filename string  `json:"filename"`
+		Location struct {
+			Row    int `json:"row"`
+			Column int `json:"column"`
+		} `json:"location"`
+		EndLocation struct {
+			Row    int `json:"row"`
+			Column int `json:"column"`
+		} `json:"end_location"`
+		Fix *struct {
+			Message string `json:"message"`
+		} `json:"fix"`
+	}
+	if err := json.Unmarshal(stdout, &diagnostics); err != nil {
+		return nil, fmt.Errorf("invalid ruff output: %w", err)
+	}
+	var findings []Finding
+	for _, d := range diagnostics {
+		// Syntax errors have no code.
+		code := "syntax-error"
+		if d.Code != nil {
+			code = *d.Code
+		}
+		f := Finding{
+			Rule:      "lint/ruff/" + code,
+			Severity:  "warning",
+			Message:   d.Message,
+			File:      d.Filename,
+			Line:      d.Location.Row,
+			Column:    d.Location.Column,
+			EndLine:   d.EndLocation.Row,
+			EndColumn: d.EndLocation.Column,
+		}
+		if d.Fix != nil {
+			f.Remediation = d.Fix.Message
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// parseGolangciLint reads golangci-lint run --out-format json.
+func parseGolangciLint(stdout, _ []byte) ([]Finding, error) {
+	var out struct {
+		Issues []struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Severity   string `json:"Severity"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+			LineRange *struct {
+				From int `json:"From"`
+				To   int `json:"To"`
+			} `json:"LineRange"`
+			Replacement *struct {
+				NeedOnlyDelete bool     `json:"NeedOnlyDelete"`
+				NewLines       []string `json:"NewLines"`
+			} `json:"Replacement"`
+		} `json:"Issues"`
+	}
+	// golangci-lint may print a summary after the JSON document.
+	if err := json.NewDecoder(bytes.NewReader(stdout)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid golangci-lint output: %w", err)
+	}
+	var findings []Finding
+	for _, issue := range out.Issues {
+		f := Finding{
+			Rule:     "lint/golangci-lint/" + issue.FromLinter,
+			Severity: "warning",
+			Message:  issue.Text,
+			File:     issue.Pos.Filename,
+			Line:     issue.Pos.Line,
+			Column:   issue.Pos.Column,
+		}
+		if strings.EqualFold(issue.Severity, "info") {
+			f.Severity = "info"
+		}
+		if issue.LineRange != nil && issue.LineRange.To > issue.LineRange.From {
+			f.EndLine = issue.LineRange.To
+		}
+		if r := issue.Replacement; r != nil && (r.NeedOnlyDelete || len(r.NewLines) > 0) {
+			f.Suggestion = strings.Join(r.NewLines, "\n")
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// parseESLint reads eslint -f json. ESLint errors warn and ESLint warnings are
+// informational.
+func parseESLint(stdout, _ []byte) ([]Finding, error) {
+	var files []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID    *string `json:"ruleId"`
+			Severity  int     `json:"severity"`
+			Message   string  `json:"message"`
+			Line      int     `json:"line"`
+			Column    int     `json:"column"`
+			EndLine   int     `json:"endLine"`
+			EndColumn int     `json:"endColumn"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(stdout, &files); err != nil {
+		return nil, fmt.Errorf("invalid eslint output: %w", err)
+	}
+	var findings []Finding
+	for _, file := range files {
+		for _, m := range file.Messages {
+			// Parse errors have no rule.
+			rule := "parse-error"
+			if m.RuleID != nil {
+				rule = *m.RuleID
+			}
+			severity := "warning"
+			if m.Severity < 2 {
+				severity = "info"
+			}
+			findings = append(findings, Finding{
+				Rule:      "lint/eslint/" + rule,
+				Severity:  severity,
+				Message:   m.Message,
+				File:      file.FilePath,
+				Line:      m.Line,
+				Column:    m.Column,
+				EndLine:   m.EndLine,
+				EndColumn: m.EndColumn,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseGoVet reads go vet -json. It writes one JSON object per package,
+// preceded by a "# package" comment line, to stdout or, before Go 1.24, to
+// stderr:
+//
+//	{"pkg": {"analyzer": [{"posn": "file.go:10:2", "message": "..."}]}}
+func parseGoVet(stdout, stderr []byte) ([]Finding, error) {
+	out := stdout
+	if !bytes.Contains(out, []byte("{")) {
+		out = stderr
+	}
+	var doc bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), "#") {
+			doc.Write(scanner.Bytes())
+			doc.WriteByte('\n')
+		}
+	}
+
+	var findings []Finding
+	dec := json.NewDecoder(&doc)
+	for {
+		var pkgs map[string]map[string]json.RawMessage
+		err := dec.Decode(&pkgs)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Build errors are printed as plain text.
+			return nil, fmt.Errorf("go vet failed: %s", strings.TrimSpace(string(stderr)))
+		}
+		for _, analyzers := range pkgs {
+			for analyzer, raw := range analyzers {
+				var diagnostics []struct {
+					Posn    string `json:"posn"`
+					End     string `json:"end"`
+					Message string `json:"message"`
+				}
+				// Analyzers that fail report {"error": "..."} instead.
+				if json.Unmarshal(raw, &diagnostics) != nil {
+					continue
+				}
+				for _, d := range diagnostics {
+					file, line, col := splitPosition(d.Posn)
+					_, endLine, endCol := splitPosition(d.End)
+					findings = append(findings, Finding{
+						Rule:      "lint/go-vet/" + analyzer,
+						Severity:  "warning",
+						Message:   d.Message,
+						File:      file,
+						Line:      line,
+						Column:    col,
+						EndLine:   endLine,
+						EndColumn: endCol,
+					})
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// parseStaticcheck reads staticcheck -f json, one JSON object per line.
+func parseStaticcheck(stdout, _ []byte) ([]Finding, error) {
+	var findings []Finding
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var d struct {
+			Code     string `json:"code"`
+			Severity string `json:"severity"`
+			Message  string `json:"message"`
+			Location struct {
+				File   string `json:"file"`
+				Line   int    `json:"line"`
+				Column int    `json:"column"`
+			} `json:"location"`
+			End struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"end"`
+		}
+		err := dec.Decode(&d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid staticcheck output: %w", err)
+		}
+		// "compile" diagnostics are build errors, not findings.
+		if d.Code == "compile" {
+			return nil, fmt.Errorf("staticcheck failed: %s", d.Message)
+		}
+		findings = append(findings, Finding{
+			Rule:      "lint/staticcheck/" + d.Code,
+			Severity:  lintLevel(d.Severity),
+			Message:   d.Message,
+			File:      d.Location.File,
+			Line:      d.Location.Line,
+			Column:    d.Location.Column,
+			EndLine:   d.End.Line,
+			EndColumn: d.End.Column,
+		})
+	}
+	return findings, nil
+}
+
+// tscLine matches a diagnostic of tsc --pretty false:
+// "file(line,col): error TS2322: message".
+var tscLine = regexp.MustCompile(`^(.+?)\((\d+),(\d+)\): (error|warning) (TS\d+): (.*)$`)
+
+// parseTsc reads tsc --pretty false. Diagnostics without a location, such as
+// a missing tsconfig.json, are ignored.
+func parseTsc(stdout, _ []byte) ([]Finding, error) {
+	var findings []Finding
+	for _, line := range strings.Split(string(stdout), "\n") {
+		m := tscLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		findings = append(findings, Finding{
+			Rule:     "lint/tsc/" + m[5],
+			Severity: lintLevel(m[4]),
+			Message:  m[6],
+			File:     m[1],
+			Line:     lineNo,
+			Column:   col,
+		})
+	}
+	return findings, nil
+}
+
+// parseShellcheck reads shellcheck -f json.
+func parseShellcheck(stdout, _ []byte) ([]Finding, error) {
+	var diagnostics []struct {
+		File      string `json:"file"`
+		Line      int    `json:"line"`
+		EndLine   int    `json:"endLine"`
+		Column    int    `json:"column"`
+		EndColumn int    `json:"endColumn"`
+		Level     string `json:"level"`
+		Code      int    `json:"code"`
+		Message   string `json:"message"`
+	}
+	if err := json.Unmarshal(stdout, &diagnostics); err != nil {
+		return nil, fmt.Errorf("invalid shellcheck output: %w", err)
+	}
+	var findings []Finding
+	for _, d := range diagnostics {
+		findings = append(findings, Finding{
+			Rule:      fmt.Sprintf("lint/shellcheck/SC%d", d.Code),
+			Severity:  lintLevel(d.Level),
+			Message:   d.Message,
+			File:      d.File,
+			Line:      d.Line,
+			Column:    d.Column,
+			EndLine:   d.EndLine,
+			EndColumn: d.EndColumn,
+		})
+	}
+	return findings, nil
+}
+
+// parseHadolint reads hadolint -f json.
+func parseHadolint(stdout, _ []byte) ([]Finding, error) {
+	var diagnostics []struct {
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Level   string `json:"level"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(stdout, &diagnostics); err != nil {
+		return nil, fmt.Errorf("invalid hadolint output: %w", err)
+	}
+	var findings []Finding
+	for _, d := range diagnostics {
+		findings = append(findings, Finding{
+			Rule:     "lint/hadolint/" + d.Code,
+			Severity: lintLevel(d.Level),
+			Message:  d.Message,
+			File:     d.File,
+			Line:     d.Line,
+			Column:   d.Column,
+		})
+	}
+	return findings, nil
+}
+
+// yamllintLine matches yamllint -f parsable:
+// "file:line:col: [error] message (rule)".
+var yamllintLine = regexp.MustCompile(`^(.+?):(\d+):(\d+): \[(\w+)\] (.*?)(?: \(([\w-]+)\))?$`)
+
+// parseYamllint reads yamllint -f parsable.
+func parseYamllint(stdout, _ []byte) ([]Finding, error) {
+	var findings []Finding
+	for _, line := range strings.Split(string(stdout), "\n") {
+		m := yamllintLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		rule := m[6]
+		if rule == "" {
+			rule = "syntax"
+		}
+		findings = append(findings, Finding{
+			Rule:     "lint/yamllint/" + rule,
+			Severity: lintLevel(m[4]),
+			Message:  m[5],
+			File:     m[1],
+			Line:     lineNo,
+			Column:   col,
+		})
+	}
+	return findings, nil
+}
+
+// parseMarkdownlint reads markdownlint --json, which writes to stderr.
+func parseMarkdownlint(stdout, stderr []byte) ([]Finding, error) {
+	out := stderr
+	if len(bytes.TrimSpace(out)) == 0 {
+		out = stdout
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var diagnostics []struct {
+		FileName        string   `json:"fileName"`
+		LineNumber      int      `json:"lineNumber"`
+		RuleNames       []string `json:"ruleNames"`
+		RuleDescription string   `json:"ruleDescription"`
+		ErrorDetail     string   `json:"errorDetail"`
+		ErrorRange      []int    `json:"errorRange"`
+	}
+	if err := json.Unmarshal(out, &diagnostics); err != nil {
+		return nil, fmt.Errorf("invalid markdownlint output: %w", err)
+	}
+	var findings []Finding
+	for _, d := range diagnostics {
+		f := Finding{
+			Rule:     "lint/markdownlint",
+			Severity: "warning",
+			Message:  d.RuleDescription,
+			File:     d.FileName,
+			Line:     d.LineNumber,
+		}
+		if len(d.RuleNames) > 0 {
+			f.Rule += "/" + d.RuleNames[0]
+		}
+		if d.ErrorDetail != "" {
+			f.Message += ": " + d.ErrorDetail
+		}
+		if len(d.ErrorRange) == 2 {
+			f.Column = d.ErrorRange[0]
+			f.EndLine = d.LineNumber
+			f.EndColumn = d.ErrorRange[0] + d.ErrorRange[1] - 1
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// textLine matches the "file:line[:col]: message" lines most compilers and
+// linters can print.
+var textLine = regexp.MustCompile(`^(.+?):(\d+)(?::(\d+))?:\s*(.+)$`)
+
+// parseText reads "file:line[:col]: message" lines from stdout and stderr,
+// for tools without a dedicated parser. The rule is the tool name.
+func parseText(stdout, stderr []byte) ([]Finding, error) {
+	var findings []Finding
+	for _, line := range strings.Split(string(stdout)+"\n"+string(stderr), "\n") {
+		m := textLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		findings = append(findings, Finding{
+			Severity: "warning",
+			Message:  m[4],
+			File:     m[1],
+			Line:     lineNo,
+			Column:   col,
+		})
+	}
+	return findings, nil
+}
+
+// splitPosition splits "file:line:col" or "file:line" as printed by Go
+// tools.
+func splitPosition(posn string) (file string, line, col int) {
+	file = posn
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndexByte(file, ':')
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(file[i+1:])
+		if err != nil {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		file = file[:i]
+	}
+	if len(nums) > 0 {
+		line = nums[0]
+	}
+	if len(nums) > 1 {
+		col = nums[1]
+	}
+	return file, line, col
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Dependency is a package pinned to a version by a dependency manifest.
+type Dependency struct {
+	Ecosystem string `json:"ecosystem"` // OSV ecosystem name: "Go", "npm", "PyPI"
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+}
+
+// manifestEcosystems maps the manifest file names we understand to the
+// ecosystem of the dependencies they declare.
+var manifestEcosystems = map[string]string{
+	"go.mod":            "Go",
+	"go.sum":            "Go",
+	"package-lock.json": "npm",
+	"requirements.txt":  "PyPI",
+}
+
+// isDependencyManifest reports whether file is a manifest parseManifest reads.
+func isDependencyManifest(file string) bool {
+	_, ok := manifestEcosystems[path.Base(file)]
+	return ok
+}
+
+// parseManifest returns the pinned dependencies declared in a manifest, keyed
+// by package name. A package may appear with several versions (e.g. nested
+// npm packages), so each name maps to a set of versions.
+func parseManifest(file string, content []byte) (map[string]map[string]bool, error) {
+	deps := make(map[string]map[string]bool)
+	add := func(name, version string) {
+		if name == "" || version == "" {
+			return
+		}
+		if deps[name] == nil {
+			deps[name] = make(map[string]bool)
+		}
+		deps[name][version] = true
+	}
+
+	switch path.Base(file) {
+	case "go.mod":
+		f, err := modfile.ParseLax(file, content, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range f.Require {
+			add(r.Mod.Path, r.Mod.Version)
+		}
+		for _, r := range f.Replace {
+			if r.New.Version != "" {
+				delete(deps, r.Old.Path)
+				add(r.New.Path, r.New.Version)
+			}
+		}
+	case "go.sum":
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+				continue
+			}
+			add(fields[0], fields[1])
+		}
+	case "package-lock.json":
+		var lock struct {
+			Packages map[string]struct {
+				Version string `json:"version"`
+				Link    bool   `json:"link"`
+			} `json:"packages"`
+			Dependencies map[string]npmLockDependency `json:"dependencies"`
+		}
+		if err := json.Unmarshal(content, &lock); err != nil {
+			return nil, err
+		}
+		if len(lock.Packages) > 0 {
+			// lockfileVersion 2 and 3: keys are install paths.
+			for key, pkg := range lock.Packages {
+				i := strings.LastIndex(key, "node_modules/")
+				if i < 0 || pkg.Link {
+					continue
+				}
+				add(key[i+len("node_modules/"):], pkg.Version)
+			}
+		} else {
+			var walk func(map[string]npmLockDependency)
+			walk = func(m map[string]npmLockDependency) {
+				for name, dep := range m {
+					add(name, dep.Version)
+					walk(dep.Dependencies)
+				}
+			}
+			walk(lock.Dependencies)
+		}
+	case "requirements.txt":
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "-") {
+				continue
+			}
+			if i := strings.IndexByte(line, ';'); i >= 0 {
+				line = strings.TrimSpace(line[:i]) // environment marker
+			}
+			// Only exact pins identify a version we can check.
+			name, version, ok := strings.Cut(line, "==")
+			if !ok {
+				continue
+			}
+			name = strings.TrimSpace(name)
+			if i := strings.IndexByte(name, '['); i >= 0 {
+				name = name[:i] // extras
+			}
+			add(strings.TrimSpace(name), strings.TrimSpace(strings.TrimPrefix(version, "=")))
+		}
+	}
+	return deps, nil
+}
+
+type npmLockDependency struct {
+	Version      string                       `json:"version"`
+	Dependencies map[string]npmLockDependency `json:"dependencies"`
+}
+
+// changedDependencies compares two versions of a manifest and returns the
+// dependencies that were added or moved to a new version.
+func changedDependencies(file string, before, after []byte) ([]Dependency, error) {
+	oldDeps, err := parseManifest(file, before)
+	if err != nil {
+		return nil, err
+	}
+	newDeps, err := parseManifest(file, after)
+	if err != nil {
+		return nil, err
+	}
+
+	eco := manifestEcosystems[path.Base(file)]
+	var changed []Dependency
+	for name, versions := range newDeps {
+		for version := range versions {
+			if !oldDeps[name][version] {
+				changed = append(changed, Dependency{Ecosystem: eco, Name: name, Version: version})
+			}
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,365 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+)
+
+type MigrationsAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewMigrationsAgent(cfg *config.Config) Agent {
+	return &MigrationsAgent{
+		BaseAgent: BaseAgent{id: "migrations", description: "Flags dangerous operations in SQL schema migrations", model: "none"},
+		cfg:       cfg,
+	}
+}
+
+func (*MigrationsAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"SQL"}}
+}
+
+// MigrationsOptions configures the migrations agent under
+// agents.migrations.options.
+type MigrationsOptions struct {
+	// Dir is the directory holding the .sql migrations. Directories with the
+	// same name below the root also match.
+	Dir string `mapstructure:"dir" yaml:"dir"`
+	// Dialect is "postgres" or "mysql".
+	Dialect string `mapstructure:"dialect" yaml:"dialect"`
+}
+
+func (o MigrationsOptions) Validate() error {
+	if o.Dir == "" {
+		return errors.New("dir must not be empty")
+	}
+	if o.Dialect != "postgres" && o.Dialect != "mysql" {
+		return fmt.Errorf("unsupported dialect %q: use postgres or mysql", o.Dialect)
+	}
+	return nil
+}
+
+func (*MigrationsAgent) DefaultOptions() any {
+	return MigrationsOptions{Dir: "migrations", Dialect: "postgres"}
+}
+
+type MigrationsReport struct {
+	Dialect  string    `json:"dialect"`
+	Files    []string  `json:"files"`
+	Findings []Finding `json:"-"`
+}
+
+func (a *MigrationsAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(MigrationsOptions))
+	if err != nil {
+		return nil, err
+	}
+	dir, dialect := opts.Dir, opts.Dialect
+
+	diffs, err := patch.Parse(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	report := MigrationsReport{Dialect: dialect}
+	for _, d := range diffs {
+		file := d.Path()
+		if !isMigrationFile(file, dir) {
+			continue
+		}
+		report.Files = append(report.Files, file)
+
+		// Applied migrations are never re-run, so changing one silently
+		// diverges the schema of existing databases from new ones.
+		if d.OldPath != "" && ctx.BaseRef != "" {
+			if _, err := readFileAtRevision(repoPath, ctx.BaseRef, d.OldPath); err == nil {
+				msg := "Migration was edited after it was committed; add a new migration instead"
+				if d.NewPath == "" {
+					msg = "Committed migration was deleted; add a new migration instead"
+				}
+				report.Findings = append(report.Findings, Finding{Rule: "migrations/edited", Severity: "blocking", Message: msg, File: file})
+				continue
+			}
+		}
+		if d.NewPath == "" || strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+
+		content, err := readChangedFile(repoPath, ctx.HeadRef, file)
+		if err != nil {
+			continue
+		}
+		up, hasDown := splitDownMigration(string(content))
+		if !hasDown && strings.HasSuffix(file, ".up.sql") {
+			downFile := strings.TrimSuffix(file, ".up.sql") + ".down.sql"
+			_, err := readChangedFile(repoPath, ctx.HeadRef, downFile)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			hasDown = err == nil
+		}
+		if !hasDown {
+			report.Findings = append(report.Findings, Finding{Rule: "migrations/missing-down", Severity: "warning", Message: "Migration has no down migration", File: file, Line: 1})
+		}
+
+		for _, f := range checkMigration(up, dialect) {
+			f.File = file
+			report.Findings = append(report.Findings, f)
+		}
+	}
+
+	if len(report.Files) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No migrations changed"})
+		return &res, nil
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	res := a.CreateResult(AgentResult{Data: report, Findings: report.Findings, Severity: severity})
+	return &res, nil
+}
+
+// FindingsSeverity blocks on a blocking finding and warns on any other.
+func (*MigrationsAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		severity = "warning"
+	}
+	return severity
+}
+
+// isMigrationFile reports whether file is a .sql file directly inside a
+// directory named dir, at the root or below it.
+func isMigrationFile(file, dir string) bool {
+	if path.Ext(file) != ".sql" {
+		return false
+	}
+	parent := path.Dir(file)
+	return parent == dir || strings.HasSuffix(parent, "/"+dir)
+}
+
+// downMarker matches the section markers of goose, dbmate and sql-migrate.
+var downMarker = regexp.MustCompile(`(?im)^\s*--\s*(\+goose\s+down|migrate:down|\+migrate\s+down)\b`)
+
+// splitDownMigration returns the up part of a migration and whether the file
+// also carries a down section.
+func splitDownMigration(content string) (string, bool) {
+	if loc := downMarker.FindStringIndex(content); loc != nil {
+		return content[:loc[0]], true
+	}
+	return content, false
+}
+
+// sqlStatement is one statement with its comments stripped and whitespace
+// collapsed, and the line it starts on.
+type sqlStatement struct {
+	Text string
+	Line int
+}
+
+// splitSQL splits a script into statements, skipping comments, quoted strings
+// and dollar-quoted bodies when looking for semicolons.
+func splitSQL(script string) []sqlStatement {
+	var stmts []sqlStatement
+	var sb strings.Builder
+	line, start := 1, 0
+
+	flush := func() {
+		text := strings.Join(strings.Fields(sb.String()), " ")
+		if text != "" {
+			stmts = append(stmts, sqlStatement{Text: text, Line: start})
+		}
+		sb.Reset()
+		start = 0
+	}
+	write := func(s string) {
+		if start == 0 && strings.TrimSpace(s) != "" {
+			start = line
+		}
+		sb.WriteString(s)
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		rest := script[i:]
+		switch {
+		case c == '\n':
+			sb.WriteByte(' ')
+			line++
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end - 1
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest, "*/")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			line += strings.Count(rest[:end+2], "\n")
+			sb.WriteByte(' ')
+			i += end + 1
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(rest[1:], c)
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			write(rest[:end+2])
+			line += strings.Count(rest[:end+2], "\n")
+			i += end + 1
+		case c == '$':
+			tag := dollarTag.FindString(rest)
+			if tag == "" {
+				write("$")
+				continue
+			}
+			end := strings.Index(rest[len(tag):], tag)
+			if end < 0 {
+				end = len(rest) - 2*len(tag)
+			}
+			body := rest[:len(tag)+end+len(tag)]
+			write(body)
+			line += strings.Count(body, "\n")
+			i += len(body) - 1
+		case c == ';':
+			flush()
+		default:
+			write(string(c))
+		}
+	}
+	flush()
+	return stmts
+}
+
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+var (
+	sqlCreateTable  = regexp.MustCompile(`(?i)^CREATE (?:TEMP(?:ORARY)? |UNLOGGED )?TABLE (?:IF NOT EXISTS )?([\w."` + "`" + `]+)`)
+	sqlAlterTable   = regexp.MustCompile(`(?i)^ALTER TABLE (?:ONLY )?(?:IF EXISTS )?([\w."` + "`" + `]+) (.*)$`)
+	sqlCreateIndex  = regexp.MustCompile(`(?i)^CREATE (?:UNIQUE )?INDEX (CONCURRENTLY )?.*? ON (?:ONLY )?([\w."` + "`" + `]+)`)
+	sqlDropTable    = regexp.MustCompile(`(?i)^DROP TABLE\b`)
+	sqlTruncate     = regexp.MustCompile(`(?i)^TRUNCATE\b`)
+	sqlDropColumn   = regexp.MustCompile(`(?i)^DROP (?:COLUMN )?(?:IF EXISTS )?[\w"` + "`" + `]+(?: CASCADE| RESTRICT)?$`)
+	sqlAddColumn    = regexp.MustCompile(`(?i)^ADD (?:COLUMN )?(?:IF NOT EXISTS )?[\w"` + "`" + `]+ `)
+	sqlAddNonColumn = regexp.MustCompile(`(?i)^ADD (CONSTRAINT|INDEX|KEY|PRIMARY|UNIQUE|FOREIGN|CHECK|FULLTEXT|SPATIAL)\b`)
+	sqlColumnType   = regexp.MustCompile(`(?i)^ALTER (?:COLUMN )?[\w"` + "`" + `]+ (?:SET DATA )?TYPE\b`)
+	sqlSetNotNull   = regexp.MustCompile(`(?i)^ALTER (?:COLUMN )?[\w"` + "`" + `]+ SET NOT NULL\b`)
+	sqlMySQLModify  = regexp.MustCompile(`(?i)^(MODIFY|CHANGE)\b`)
+	sqlMySQLOnline  = regexp.MustCompile(`(?i)\bALGORITHM\s*=\s*(INPLACE|INSTANT)\b|\bLOCK\s*=\s*NONE\b`)
+)
+
+// checkMigration returns the dangerous operations in the up part of a
+// migration. Operations on tables created by the same migration are safe.
+func checkMigration(script, dialect string) []Finding {
+	var findings []Finding
+	add := func(line int, rule, severity, msg string) {
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Message: msg, Line: line})
+	}
+	created := make(map[string]bool)
+
+	for _, stmt := range splitSQL(script) {
+		text := stmt.Text
+		switch {
+		case sqlCreateTable.MatchString(text):
+			created[tableKey(sqlCreateTable.FindStringSubmatch(text)[1])] = true
+		case sqlDropTable.MatchString(text):
+			add(stmt.Line, "migrations/drop-table", "blocking", "DROP TABLE deletes data and breaks code still reading the table")
+		case sqlTruncate.MatchString(text):
+			add(stmt.Line, "migrations/drop-table", "blocking", "TRUNCATE deletes all rows of the table")
+		case sqlCreateIndex.MatchString(text):
+			m := sqlCreateIndex.FindStringSubmatch(text)
+			if created[tableKey(m[2])] {
+				continue
+			}
+			if dialect == "postgres" && m[1] == "" {
+				add(stmt.Line, "migrations/index-lock", "warning", "CREATE INDEX without CONCURRENTLY blocks writes to the table while it builds")
+			}
+			if dialect == "mysql" && !sqlMySQLOnline.MatchString(text) {
+				add(stmt.Line, "migrations/index-lock", "warning", "CREATE INDEX without ALGORITHM=INPLACE, LOCK=NONE may block writes while it builds")
+			}
+		case sqlAlterTable.MatchString(text):
+			m := sqlAlterTable.FindStringSubmatch(text)
+			if created[tableKey(m[1])] {
+				continue
+			}
+			online := sqlMySQLOnline.MatchString(text)
+			for _, clause := range splitTopLevel(m[2]) {
+				findings = append(findings, checkAlterClause(clause, dialect, online, stmt.Line)...)
+			}
+		}
+	}
+	return findings
+}
+
+// checkAlterClause checks one comma-separated clause of an ALTER TABLE.
+func checkAlterClause(clause, dialect string, online bool, line int) []Finding {
+	upper := strings.ToUpper(clause)
+	finding := func(rule, severity, msg string) []Finding {
+		return []Finding{{Rule: rule, Severity: severity, Message: msg, Line: line}}
+	}
+
+	switch {
+	case sqlDropColumn.MatchString(clause) && !strings.HasPrefix(upper, "DROP CONSTRAINT") &&
+		!strings.HasPrefix(upper, "DROP INDEX") && !strings.HasPrefix(upper, "DROP KEY") &&
+		!strings.HasPrefix(upper, "DROP PRIMARY") && !strings.HasPrefix(upper, "DROP FOREIGN"):
+		return finding("migrations/drop-column", "blocking", "Dropping a column deletes data and breaks code still reading it")
+	case sqlAddNonColumn.MatchString(clause):
+		if dialect == "mysql" && strings.Contains(upper, "INDEX") && !online {
+			return finding("migrations/index-lock", "warning", "ADD INDEX without ALGORITHM=INPLACE, LOCK=NONE may block writes while it builds")
+		}
+	case sqlAddColumn.MatchString(clause):
+		if strings.Contains(upper, "NOT NULL") && !strings.Contains(upper, "DEFAULT") {
+			return finding("migrations/not-null-without-default", "blocking", "Adding a NOT NULL column without a DEFAULT fails on tables with rows")
+		}
+	case sqlColumnType.MatchString(clause):
+		return finding("migrations/table-rewrite", "warning", "Changing a column type rewrites the whole table under an exclusive lock")
+	case dialect == "postgres" && sqlSetNotNull.MatchString(clause):
+		return finding("migrations/table-rewrite", "warning", "SET NOT NULL scans the whole table under an exclusive lock; add a NOT VALID check constraint first")
+	case dialect == "mysql" && sqlMySQLModify.MatchString(clause) && !online:
+		return finding("migrations/table-rewrite", "warning", "MODIFY/CHANGE COLUMN may copy the whole table; use ALGORITHM=INPLACE or INSTANT where possible")
+	}
+	return nil
+}
+
+// splitTopLevel splits s on commas outside parentheses.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+func tableKey(name string) string {
+	return strings.ToLower(strings.Trim(name, "\"`"))
+}
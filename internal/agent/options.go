@@ -0,0 +1,311 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// Configurable is implemented by agents with typed settings under
+// agents.<id>.options. DefaultOptions returns the options struct holding the
+// defaults; configured values are decoded over it by agentOptions.
+type Configurable interface {
+	DefaultOptions() any
+}
+
+// Thresholder is implemented by agents with numeric limits that
+// agents.<id>.thresholds can override, keyed by name.
+type Thresholder interface {
+	DefaultThresholds() map[string]float64
+}
+
+// FindingsSeverity is implemented by agents whose result severity follows
+// from their findings alone. When findings outside the change are hidden, the
+// runner recomputes the severity from those left; other agents keep theirs,
+// since it also reflects a score or delta the hidden findings do not change.
+type FindingsSeverity interface {
+	FindingsSeverity(findings []Finding) string
+}
+
+// optionsValidator is implemented by options that check their values.
+type optionsValidator interface {
+	Validate() error
+}
+
+// agentOptions decodes agents.<id>.options over defaults and validates the
+// result. Unknown keys are an error.
+func agentOptions[T any](cfg *config.Config, id string, defaults T) (T, error) {
+	opts := defaults
+	if err := decodeOptions(cfg.Agents[id].Options, &opts); err != nil {
+		return opts, fmt.Errorf("invalid agents.%s.options: %w", id, err)
+	}
+	return opts, nil
+}
+
+func decodeOptions(raw map[string]any, target any) error {
+	if len(raw) > 0 {
+		if err := decodeRaw(raw, target); err != nil {
+			return err
+		}
+	}
+	if v, ok := target.(optionsValidator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func decodeRaw(raw map[string]any, target any) error {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(target).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		known[t.Field(i).Tag.Get("mapstructure")] = true
+	}
+	var unknown []string
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown option %s", strings.Join(unknown, ", "))
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           target,
+		WeaklyTypedInput: true,
+		// Replace default lists rather than merging into them.
+		ZeroFields: true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(raw); err != nil {
+		// Drop mapstructure's "decoding failed due to the following error(s)" preamble.
+		msg := err.Error()
+		if _, rest, ok := strings.Cut(msg, "\n\n"); ok {
+			msg = strings.ReplaceAll(strings.TrimSpace(rest), "\n", "; ")
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// agentThreshold returns agents.<id>.thresholds.<name>, or def when it is
+// not set.
+func agentThreshold(cfg *config.Config, id, name string, def float64) float64 {
+	if v, ok := cfg.Agents[id].Thresholds[name]; ok {
+		return v
+	}
+	return def
+}
+
+// configFor returns cfg as the agent with the given ID sees it: with
+// agents.<id>.model in place of models.primary.
+func configFor(id string, cfg *config.Config) *config.Config {
+	model := cfg.Agents[id].Model
+	if model == "" {
+		return cfg
+	}
+	c := *cfg
+	c.Models.Primary = model
+	return &c
+}
+
+// filterContext narrows the changed files and the diff to those selected by
+// agents.<id>.include and exclude. It reports false when the change had files
+// but none are left.
+func filterContext(ctx AgentContext, settings config.AgentSettings) (AgentContext, bool) {
+	if len(settings.Include) == 0 && len(settings.Exclude) == 0 {
+		return ctx, true
+	}
+	selected := func(file string) bool {
+		return (len(settings.Include) == 0 || matchAnyGlob(settings.Include, file)) && !matchAnyGlob(settings.Exclude, file)
+	}
+
+	var files []string
+	for _, file := range ctx.Files {
+		if selected(file) {
+			files = append(files, file)
+		}
+	}
+	if len(ctx.Files) > 0 && len(files) == 0 {
+		return ctx, false
+	}
+	ctx.Files = files
+	ctx.Diff = filterDiff(ctx.Diff, selected)
+	return ctx, true
+}
+
+// filterDiff keeps the file sections of a git diff whose path satisfies keep.
+func filterDiff(diff string, keep func(path string) bool) string {
+	var sb strings.Builder
+	include := true
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			path := strings.TrimSpace(line[strings.LastIndex(line, " b/")+3:])
+			include = keep(path)
+		}
+		if include {
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+// filterNewCode drops the findings of result outside the change when mode is
+// "changed-files" or "changed-lines", counting them in HiddenFindings.
+// Findings without a file are kept. When findings are hidden and a
+// implements FindingsSeverity, the severity is recomputed from those left.
+// Agents that summarise their findings in Data filter them with
+// newCodeFindings themselves, leaving nothing for this to hide.
+func filterNewCode(a Agent, result *AgentResult, ctx AgentContext, mode string) {
+	kept, hidden := newCodeFindings(result.Findings, ctx, mode)
+	if hidden == 0 {
+		return
+	}
+	result.Findings = kept
+	result.HiddenFindings += hidden
+	if s, ok := a.(FindingsSeverity); ok {
+		result.Severity = s.FindingsSeverity(kept)
+	}
+}
+
+// newCodeFindings splits findings into those inside the change under mode and
+// the number outside it. Line numbers are those of ctx.Diff's new side, i.e.
+// the staged content or HeadRef.
+func newCodeFindings(findings []Finding, ctx AgentContext, mode string) ([]Finding, int) {
+	if mode == "" || mode == "all" || len(findings) == 0 {
+		return findings, 0
+	}
+	files := make(map[string]bool)
+	for _, file := range ctx.Files {
+		files[filepath.ToSlash(file)] = true
+	}
+	var changed map[string][]int
+	if mode == "changed-lines" {
+		// An unparsable diff leaves no changed lines, hiding every finding.
+		changed, _ = patch.ChangedLines(ctx.Diff)
+	}
+
+	var kept []Finding
+	hidden := 0
+	for _, f := range findings {
+		keep := f.File == "" || files[f.File]
+		if keep && mode == "changed-lines" && f.File != "" && f.Line > 0 {
+			keep = touchesLines(changed[f.File], f.Line, max(f.Line, f.EndLine))
+		}
+		if keep {
+			kept = append(kept, f)
+		} else {
+			hidden++
+		}
+	}
+	return kept, hidden
+}
+
+// touchesLines reports whether any of lines is within start and end.
+func touchesLines(lines []int, start, end int) bool {
+	for _, l := range lines {
+		if l >= start && l <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAgentSettings checks agents.<id> against the agent: the timeout,
+// globs, threshold names and typed options. It returns one message per
+// problem.
+func ValidateAgentSettings(id string, cfg *config.Config) []string {
+	settings := cfg.Agents[id]
+	a, err := GetAgent(id, configFor(id, cfg))
+	if err != nil {
+		return []string{fmt.Sprintf("agents.%s: no such agent", id)}
+	}
+
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("agents.%s.", id)+fmt.Sprintf(format, args...))
+	}
+	if settings.Timeout != "" {
+		if d, err := time.ParseDuration(settings.Timeout); err != nil || d <= 0 {
+			add("timeout: %q is not a positive duration", settings.Timeout)
+		}
+	}
+	switch settings.NewCode {
+	case "", "all", "changed-files", "changed-lines":
+	default:
+		add("new_code: %q is not all, changed-files or changed-lines", settings.NewCode)
+	}
+	if settings.Model != "" && a.Model() == "none" {
+		add("model: %s does not use a model", id)
+	}
+	for _, glob := range append(append([]string(nil), settings.Include...), settings.Exclude...) {
+		if _, err := globRegexp(glob); err != nil {
+			add("include/exclude: invalid glob %q: %v", glob, err)
+		}
+	}
+
+	known := map[string]float64{}
+	if t, ok := a.(Thresholder); ok {
+		known = t.DefaultThresholds()
+	}
+	var names []string
+	for name := range settings.Thresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := known[name]; !ok {
+			add("thresholds: unknown threshold %q%s", name, knownNames(known))
+		}
+	}
+
+	if c, ok := a.(Configurable); ok {
+		target := reflect.New(reflect.TypeOf(c.DefaultOptions()))
+		target.Elem().Set(reflect.ValueOf(c.DefaultOptions()))
+		if err := decodeOptions(settings.Options, target.Interface()); err != nil {
+			add("options: %v", err)
+		}
+	} else if len(settings.Options) > 0 {
+		add("options: %s has no options", id)
+	}
+	return problems
+}
+
+// ValidateAgentsConfig checks every entry of the agents config section,
+// including that it names a registered agent.
+func ValidateAgentsConfig(cfg *config.Config) []string {
+	var ids []string
+	for id := range cfg.Agents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var problems []string
+	for _, id := range ids {
+		problems = append(problems, ValidateAgentSettings(id, cfg)...)
+	}
+	return problems
+}
+
+func knownNames(known map[string]float64) string {
+	if len(known) == 0 {
+		return ", the agent has none"
+	}
+	var names []string
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return ", use one of " + strings.Join(names, ", ")
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+)
+
+const newCodeDiff = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -10,2 +10,3 @@
+ x
++y
+ z
+`
+
+func TestNewCodeFindings(t *testing.T) {
+	ctx := AgentContext{Files: []string{"a.go"}, Diff: newCodeDiff}
+	findings := []Finding{
+		{Rule: "r", File: "a.go", Line: 11},
+		{Rule: "r", File: "a.go", Line: 10},
+		{Rule: "r", File: "a.go", Line: 9, EndLine: 12},
+		{Rule: "r", File: "b.go", Line: 11},
+		{Rule: "r"},
+		{Rule: "r", File: "a.go"},
+	}
+	tests := []struct {
+		mode       string
+		wantLines  []int
+		wantHidden int
+	}{
+		{"", []int{11, 10, 9, 11, 0, 0}, 0},
+		{"all", []int{11, 10, 9, 11, 0, 0}, 0},
+		{"changed-files", []int{11, 10, 9, 0, 0}, 1},
+		{"changed-lines", []int{11, 9, 0, 0}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			kept, hidden := newCodeFindings(findings, ctx, tt.mode)
+			var lines []int
+			for _, f := range kept {
+				lines = append(lines, f.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) || hidden != tt.wantHidden {
+				t.Errorf("newCodeFindings() kept lines %v, hid %d, want %v and %d", lines, hidden, tt.wantLines, tt.wantHidden)
+			}
+		})
+	}
+}
+
+func TestFilterNewCodeSeverity(t *testing.T) {
+	cfg := &config.Config{}
+	ctx := AgentContext{Files: []string{"a.go"}, Diff: newCodeDiff}
+	tests := []struct {
+		name  string
+		agent Agent
+		want  string
+	}{
+		// Drift blocks on its score and coverage on the delta, which the
+		// hidden findings do not change.
+		{"drift", NewDriftAgent(cfg), "blocking"},
+		{"coverage", NewCoverageAgent(cfg), "blocking"},
+		{"iac", NewIaCAgent(cfg), "warning"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &AgentResult{
+				Severity: "blocking",
+				Findings: []Finding{
+					{Rule: "r", Severity: "blocking", File: "b.go", Line: 1},
+					{Rule: "r", Severity: "warning", File: "a.go", Line: 11},
+				},
+			}
+			filterNewCode(tt.agent, result, ctx, "changed-lines")
+			if result.Severity != tt.want || len(result.Findings) != 1 || result.HiddenFindings != 1 {
+				t.Errorf("filterNewCode() severity %s, %d finding(s), %d hidden; want %s, 1 and 1", result.Severity, len(result.Findings), result.HiddenFindings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+)
+
+// PluginProtocolVersion is the version of the plugin protocol spoken by this
+// build. Plugins must answer the handshake with the same version.
+//
+// The protocol is a sequence of JSON messages on the plugin's stdin and
+// stdout:
+//
+//	verifier → plugin  {"type":"handshake","protocol_version":1}
+//	plugin → verifier  {"type":"handshake","protocol_version":1,"id":"...","description":"..."}
+//	verifier → plugin  {"type":"execute","context":{...AgentContext...}}
+//	plugin → verifier  {"type":"result","result":{...AgentResult...}}
+//
+// Issues belong in the result's "findings" array, see Finding; verifier fills
+// in missing fingerprints. A plugin may answer either request with
+// {"type":"error","error":"..."}.
+// Anything written to stderr is captured for diagnostics.
+const PluginProtocolVersion = 1
+
+// PluginDir holds executables discovered as plugin agents. The agent ID is the
+// file name without its extension.
+var PluginDir = filepath.Join(".verifier", "agents")
+
+const (
+	defaultPluginTimeout = time.Minute
+	pluginHandshakeLimit = 10 * time.Second
+	pluginExitGrace      = 2 * time.Second
+	maxPluginStderr      = 64 * 1024
+)
+
+// PluginSpec describes how to start a plugin agent.
+type PluginSpec struct {
+	ID      string
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+// PluginMessage is one message of the plugin protocol.
+type PluginMessage struct {
+	Type            string        `json:"type"` // "handshake", "execute", "result", "error"
+	ProtocolVersion int           `json:"protocol_version,omitempty"`
+	ID              string        `json:"id,omitempty"`
+	Description     string        `json:"description,omitempty"`
+	Context         *AgentContext `json:"context,omitempty"`
+	Result          *AgentResult  `json:"result,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// PluginRun records one exchange with a plugin. Fields are filled in as far as
+// the exchange got, so a failed run still shows the handshake and stderr.
+type PluginRun struct {
+	Handshake *PluginMessage
+	Result    *AgentResult
+	Stderr    string
+	Duration  time.Duration
+}
+
+type PluginAgent struct {
+	BaseAgent
+	spec PluginSpec
+}
+
+func NewPluginAgent(spec PluginSpec) Agent {
+	return &PluginAgent{
+		BaseAgent: BaseAgent{id: spec.ID, description: "Plugin agent " + spec.Command, model: "none"},
+		spec:      spec,
+	}
+}
+
+func (a *PluginAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	run, err := RunPlugin(a.spec, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if problems := ValidatePluginResult(run.Result); len(problems) > 0 {
+		return nil, fmt.Errorf("plugin %s returned an invalid result: %s", a.ID(), strings.Join(problems, "; "))
+	}
+
+	res := a.CreateResult(*run.Result)
+	if run.Stderr != "" {
+		res.Artifacts = append(res.Artifacts, AgentArtifact{Type: "stderr", Content: run.Stderr})
+	}
+	return &res, nil
+}
+
+// LoadPlugins registers the plugin agents declared in cfg.Plugins and found in
+// PluginDir.
+func LoadPlugins(cfg *config.Config) error {
+	specs, err := DiscoverPlugins(cfg)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		spec := spec
+		if err := registerExternal("plugin", spec.ID, func(_ *config.Config) Agent { return NewPluginAgent(spec) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiscoverPlugins lists the plugins declared in config and the executables in
+// PluginDir, config taking precedence for the same ID.
+func DiscoverPlugins(cfg *config.Config) ([]PluginSpec, error) {
+	byID := make(map[string]PluginSpec)
+
+	entries, err := os.ReadDir(PluginDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		name := e.Name()
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml", ".wasm":
+			continue // prompt and WASM agents
+		}
+		id := strings.TrimSuffix(name, filepath.Ext(name))
+		abs, err := filepath.Abs(filepath.Join(PluginDir, name))
+		if err != nil {
+			return nil, err
+		}
+		byID[id] = PluginSpec{ID: id, Command: abs, Timeout: defaultPluginTimeout}
+	}
+
+	for id, p := range cfg.Plugins {
+		spec := PluginSpec{ID: id, Command: p.Command, Args: p.Args, Timeout: defaultPluginTimeout}
+		if p.Command == "" {
+			return nil, fmt.Errorf("plugin %s has no command", id)
+		}
+		if p.Timeout != "" {
+			d, err := time.ParseDuration(p.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("plugin %s: invalid timeout: %w", id, err)
+			}
+			spec.Timeout = d
+		}
+		byID[id] = spec
+	}
+
+	specs := make([]PluginSpec, 0, len(byID))
+	for _, spec := range byID {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].ID < specs[j].ID })
+	return specs, nil
+}
+
+// RunPlugin starts the plugin, performs the handshake and sends ctx for
+// execution. The whole exchange is bounded by spec.Timeout.
+func RunPlugin(spec PluginSpec, ctx AgentContext) (run *PluginRun, err error) {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	run = &PluginRun{}
+	stderr := &tailBuffer{limit: maxPluginStderr}
+	cmd := exec.CommandContext(runCtx, spec.Command, spec.Args...)
+	cmd.Dir = ctx.RepoPath
+	cmd.Env = append(os.Environ(), fmt.Sprintf("VERIFIER_PLUGIN_PROTOCOL=%d", PluginProtocolVersion))
+	cmd.Stderr = stderr
+	cmd.WaitDelay = pluginExitGrace
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return run, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return run, err
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return run, fmt.Errorf("failed to start plugin %s: %w", spec.ID, err)
+	}
+	messages := make(chan PluginMessage)
+	readErr := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		dec := json.NewDecoder(stdout)
+		for {
+			var msg PluginMessage
+			if err := dec.Decode(&msg); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		close(stopped)
+		stdin.Close()
+		// Give the plugin a moment to exit on its own before killing it.
+		exited := make(chan struct{})
+		go func() { _ = cmd.Wait(); close(exited) }()
+		select {
+		case <-exited:
+		case <-time.After(pluginExitGrace):
+			cancel()
+			<-exited
+		}
+		run.Stderr = stderr.String()
+		run.Duration = time.Since(start)
+		if err != nil && strings.TrimSpace(run.Stderr) != "" {
+			err = fmt.Errorf("%w\nstderr:\n%s", err, lastLines(strings.TrimSpace(run.Stderr), 20))
+		}
+	}()
+	receive := func(phase string, limit time.Duration) (PluginMessage, error) {
+		timer := time.NewTimer(limit)
+		defer timer.Stop()
+		select {
+		case msg := <-messages:
+			if msg.Type == "error" {
+				return msg, fmt.Errorf("%s: plugin reported: %s", phase, msg.Error)
+			}
+			return msg, nil
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				err = errors.New("plugin exited without replying")
+			}
+			return PluginMessage{}, fmt.Errorf("%s: %w", phase, err)
+		case <-timer.C:
+			return PluginMessage{}, fmt.Errorf("%s: no reply within %s", phase, limit)
+		case <-runCtx.Done():
+			return PluginMessage{}, fmt.Errorf("%s: plugin timed out after %s", phase, timeout)
+		}
+	}
+	send := func(msg PluginMessage) error {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = stdin.Write(append(data, '\n'))
+		return err
+	}
+
+	if err := send(PluginMessage{Type: "handshake", ProtocolVersion: PluginProtocolVersion}); err != nil {
+		return run, fmt.Errorf("handshake: %w", err)
+	}
+	reply, err := receive("handshake", min(pluginHandshakeLimit, timeout))
+	if err != nil {
+		return run, err
+	}
+	handshake := reply
+	run.Handshake = &handshake
+	if reply.Type != "handshake" {
+		return run, fmt.Errorf("handshake: expected a handshake message, got %q", reply.Type)
+	}
+	if reply.ProtocolVersion != PluginProtocolVersion {
+		return run, fmt.Errorf("handshake: plugin speaks protocol version %d, verifier speaks %d", reply.ProtocolVersion, PluginProtocolVersion)
+	}
+
+	if err := send(PluginMessage{Type: "execute", Context: &ctx}); err != nil {
+		return run, fmt.Errorf("execute: %w", err)
+	}
+	stdin.Close()
+	reply, err = receive("execute", timeout)
+	if err != nil {
+		return run, err
+	}
+	if reply.Type != "result" || reply.Result == nil {
+		return run, fmt.Errorf("execute: expected a result message, got %q", reply.Type)
+	}
+	run.Result = reply.Result
+	return run, nil
+}
+
+// ValidatePluginResult checks a plugin result against the values the rest of
+// verifier relies on, returning one message per problem.
+func ValidatePluginResult(r *AgentResult) []string {
+	if r == nil {
+		return []string{"no result"}
+	}
+	var problems []string
+	switch r.Status {
+	case "success", "failure", "skipped":
+	default:
+		problems = append(problems, fmt.Sprintf("status %q is not success, failure or skipped", r.Status))
+	}
+	validSeverity := func(s string) bool { return s == "" || s == "info" || s == "warning" || s == "blocking" }
+	if !validSeverity(r.Severity) {
+		problems = append(problems, fmt.Sprintf("severity %q is not info, warning or blocking", r.Severity))
+	}
+	for i, f := range r.Findings {
+		if f.Severity == "" || !validSeverity(f.Severity) {
+			problems = append(problems, fmt.Sprintf("finding %d has severity %q", i, f.Severity))
+		}
+		if f.Message == "" {
+			problems = append(problems, fmt.Sprintf("finding %d has no message", i))
+		}
+	}
+	if r.TokensUsed < 0 || r.Cost < 0 {
+		problems = append(problems, "tokens_used and cost must not be negative")
+	}
+	return problems
+}
+
+// tailBuffer is an io.Writer that keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	limit int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf.Write(p)
+	if extra := t.buf.Len() - t.limit; extra > 0 {
+		t.buf.Next(extra)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.String()
+}
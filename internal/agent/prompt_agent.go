@@ -0,0 +1,268 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// PromptAgent runs an agent declared in config: it renders the prompt
+// template, asks the model and validates the JSON answer against the schema.
+type PromptAgent struct {
+	BaseAgent
+	cfg      *config.Config
+	def      config.PromptAgent
+	prompt   *template.Template
+	severity []*template.Template
+}
+
+// promptData is what prompt templates are executed over: the agent context
+// plus the changed files matching the agent's globs.
+type promptData struct {
+	AgentContext
+	MatchedFiles []string
+}
+
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"truncate": func(n int, s string) string {
+		if len(s) <= n {
+			return s
+		}
+		return s[:n]
+	},
+}
+
+// NewPromptAgent compiles the templates of a prompt agent definition.
+func NewPromptAgent(def config.PromptAgent, cfg *config.Config) (*PromptAgent, error) {
+	if def.ID == "" {
+		return nil, errors.New("prompt agent has no id")
+	}
+	if strings.TrimSpace(def.Prompt) == "" {
+		return nil, fmt.Errorf("prompt agent %s has no prompt", def.ID)
+	}
+	model := def.Model
+	if model == "" {
+		model = cfg.Models.Primary
+	}
+
+	a := &PromptAgent{
+		BaseAgent: BaseAgent{id: def.ID, description: def.Description, model: model},
+		cfg:       cfg,
+		def:       def,
+	}
+	var err error
+	if a.prompt, err = template.New(def.ID).Funcs(promptFuncs).Parse(def.Prompt); err != nil {
+		return nil, fmt.Errorf("prompt agent %s: invalid prompt template: %w", def.ID, err)
+	}
+	for i, rule := range def.Severity {
+		switch rule.Severity {
+		case "info", "warning", "blocking":
+		default:
+			return nil, fmt.Errorf("prompt agent %s: severity rule %d: severity %q is not info, warning or blocking", def.ID, i, rule.Severity)
+		}
+		t, err := template.New(fmt.Sprintf("%s-severity-%d", def.ID, i)).Funcs(promptFuncs).Parse(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("prompt agent %s: severity rule %d: %w", def.ID, i, err)
+		}
+		a.severity = append(a.severity, t)
+	}
+	for _, glob := range def.Files {
+		if _, err := globRegexp(glob); err != nil {
+			return nil, fmt.Errorf("prompt agent %s: invalid file glob %q: %w", def.ID, glob, err)
+		}
+	}
+	return a, nil
+}
+
+func (a *PromptAgent) Details() AgentDetails {
+	prompts := map[string]string{"prompt": a.def.Prompt}
+	if a.def.System != "" {
+		prompts["system"] = a.def.System
+	}
+	return AgentDetails{Prompts: prompts}
+}
+
+func (a *PromptAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	data := promptData{AgentContext: ctx}
+	for _, file := range ctx.Files {
+		if len(a.def.Files) == 0 || matchAnyGlob(a.def.Files, file) {
+			data.MatchedFiles = append(data.MatchedFiles, file)
+		}
+	}
+	if len(data.MatchedFiles) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No changed files match the agent's file globs"})
+		return &res, nil
+	}
+
+	var prompt strings.Builder
+	if err := a.prompt.Execute(&prompt, data); err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+	if a.def.Schema != nil {
+		schema, _ := json.MarshalIndent(a.def.Schema, "", "  ")
+		fmt.Fprintf(&prompt, "\n\nRespond with JSON matching this JSON schema:\n%s", schema)
+	}
+
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	response, err := p.Complete(prompt.String(), a.def.System, true)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+	tokensUsed := len(prompt.String())/4 + len(response)/4
+
+	var output any
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response as JSON: %w", err)
+	}
+	if a.def.Schema != nil {
+		if problems := validateJSONSchema(a.def.Schema, output, "$"); len(problems) > 0 {
+			return nil, fmt.Errorf("LLM response does not match the schema: %s", strings.Join(problems, "; "))
+		}
+	}
+
+	severity := "info"
+	for i, rule := range a.severity {
+		var out strings.Builder
+		if err := rule.Execute(&out, output); err != nil {
+			return nil, fmt.Errorf("severity rule %d: %w", i, err)
+		}
+		if strings.TrimSpace(out.String()) == "true" {
+			severity = a.def.Severity[i].Severity
+			break
+		}
+	}
+
+	res := a.CreateResult(AgentResult{
+		Data:       output,
+		Findings:   a.findings(output, severity),
+		Severity:   severity,
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+	})
+	return &res, nil
+}
+
+// findings reads the "findings" array of the model output, if there is one,
+// as findings. Missing rules default to the agent ID and missing severities to
+// the result severity.
+func (a *PromptAgent) findings(output any, severity string) []Finding {
+	m, ok := output.(map[string]any)
+	if !ok || m["findings"] == nil {
+		return nil
+	}
+	raw, err := json.Marshal(m["findings"])
+	if err != nil {
+		return nil
+	}
+	var findings []Finding
+	if err := json.Unmarshal(raw, &findings); err != nil {
+		return nil
+	}
+	for i := range findings {
+		if findings[i].Rule == "" {
+			findings[i].Rule = a.ID()
+		}
+		if findings[i].Severity == "" {
+			findings[i].Severity = severity
+		}
+	}
+	return findings
+}
+
+// LoadPromptAgents registers the prompt agents declared in cfg.PromptAgents
+// and in PluginDir/*.yaml, where the file name is the default ID.
+func LoadPromptAgents(cfg *config.Config) error {
+	defs := append([]config.PromptAgent(nil), cfg.PromptAgents...)
+
+	files, err := filepath.Glob(filepath.Join(PluginDir, "*.y*ml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var def config.PromptAgent
+		if err := yaml.Unmarshal(data, &def); err != nil {
+			return fmt.Errorf("invalid prompt agent %s: %w", file, err)
+		}
+		if def.ID == "" {
+			base := filepath.Base(file)
+			def.ID = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		defs = append(defs, def)
+	}
+
+	for _, def := range defs {
+		a, err := NewPromptAgent(def, cfg)
+		if err != nil {
+			return err
+		}
+		if err := registerExternal("prompt", a.ID(), func(cfg *config.Config) Agent {
+			a, _ := NewPromptAgent(def, cfg)
+			return a
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchAnyGlob reports whether file matches one of globs.
+func matchAnyGlob(globs []string, file string) bool {
+	for _, g := range globs {
+		if re, err := globRegexp(g); err == nil && re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp compiles a file glob. "**" matches across directories, "*" and
+// "?" within one path segment. A glob without a slash matches the base name
+// of a file in any directory, like .gitignore patterns.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !strings.Contains(glob, "/") {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
@@ -2,7 +2,8 @@ package agent
 
 import (
 	"fmt"
-	
+	"sort"
+
 	"github.com/autodevopsai/verifier-go/internal/config"
 )
 
@@ -10,8 +11,21 @@ var agentInitializers map[string]func(cfg *config.Config) Agent
 
 func init() {
 	agentInitializers = make(map[string]func(cfg *config.Config) Agent)
-	Register("lint", func(_ *config.Config) Agent { return NewLintAgent() })
+	Register("lint", NewLintAgent)
 	Register("security-scan", NewSecurityScanAgent)
+	Register("coverage", NewCoverageAgent)
+	Register("drift", NewDriftAgent)
+	Register("api-compat", NewAPICompatAgent)
+	Register("deps", NewDepsAgent)
+	Register("commit-msg", NewCommitMsgAgent)
+	Register("review", NewReviewAgent)
+	Register("testgen", NewTestgenAgent)
+	Register("complexity", NewComplexityAgent)
+	Register("iac", NewIaCAgent)
+	Register("migrations", NewMigrationsAgent)
+	Register("license", NewLicenseAgent)
+	Register("docs", NewDocsAgent)
+	Register("bench", NewBenchAgent)
 }
 
 // Register adds a new agent initializer to the registry.
@@ -22,6 +36,41 @@ func Register(id string, initializer func(cfg *config.Config) Agent) {
 	agentInitializers[id] = initializer
 }
 
+// externalAgents maps the agents registered from config or .verifier rather
+// than compiled in to their kind: "plugin", "wasm" or "prompt". Loading an
+// agent again is a no-op.
+var externalAgents = make(map[string]string)
+
+// registerExternal registers an agent defined outside the binary. Unlike
+// Register it reports a clash with a built-in agent as an error.
+func registerExternal(kind, id string, initializer func(cfg *config.Config) Agent) error {
+	if _, loaded := externalAgents[id]; loaded {
+		return nil
+	}
+	if _, exists := agentInitializers[id]; exists {
+		return fmt.Errorf("agent %s conflicts with an agent that is already registered", id)
+	}
+	externalAgents[id] = kind
+	agentInitializers[id] = initializer
+	return nil
+}
+
+// LoadAgents registers the agents defined outside the binary: plugin
+// executables, WASM modules and prompt agents. Commands call it after loading
+// config.
+func LoadAgents(cfg *config.Config) error {
+	if err := LoadPlugins(cfg); err != nil {
+		return fmt.Errorf("failed to load plugin agents: %w", err)
+	}
+	if err := LoadWasmAgents(cfg); err != nil {
+		return fmt.Errorf("failed to load wasm agents: %w", err)
+	}
+	if err := LoadPromptAgents(cfg); err != nil {
+		return fmt.Errorf("failed to load prompt agents: %w", err)
+	}
+	return nil
+}
+
 // GetAgent initializes and returns an agent by its ID.
 func GetAgent(id string, cfg *config.Config) (Agent, error) {
 	initializer, ok := agentInitializers[id]
@@ -31,11 +80,21 @@ func GetAgent(id string, cfg *config.Config) (Agent, error) {
 	return initializer(cfg), nil
 }
 
-// ListAgents returns a list of available agent IDs.
+// ListAgents returns the IDs of the available agents in sorted order.
 func ListAgents() []string {
 	var ids []string
 	for id := range agentInitializers {
 		ids = append(ids, id)
 	}
+	sort.Strings(ids)
 	return ids
 }
+
+// AgentKind reports how an agent is provided: "builtin", "plugin", "wasm" or
+// "prompt".
+func AgentKind(id string) string {
+	if kind, ok := externalAgents[id]; ok {
+		return kind
+	}
+	return "builtin"
+}
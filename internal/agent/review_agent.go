@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+)
+
+const reviewSystemPrompt = "You are a senior engineer doing code review. Point out real problems with concrete fixes, anchor every comment to a changed line, and avoid nitpicks."
+
+type ReviewAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewReviewAgent(cfg *config.Config) Agent {
+	return &ReviewAgent{
+		BaseAgent: BaseAgent{
+			id:          "review",
+			description: "LLM code review with line-anchored comments",
+			model:       cfg.Models.Primary,
+		},
+		cfg: cfg,
+	}
+}
+
+func (*ReviewAgent) Details() AgentDetails {
+	return AgentDetails{Prompts: map[string]string{"system": reviewSystemPrompt}}
+}
+
+// reviewContextLines is how much of the surrounding file is shown around each hunk.
+const reviewContextLines = 20
+
+// maxReviewContext caps the file context sent to the model, in bytes.
+const maxReviewContext = 60000
+
+// ReviewComment is a comment as returned by the model, before validation.
+type ReviewComment struct {
+	File       string  `json:"file"`
+	Line       int     `json:"line"`
+	EndLine    int     `json:"end_line"`
+	Hunk       string  `json:"hunk"`
+	Category   string  `json:"category"`
+	Severity   string  `json:"severity"`
+	Confidence float64 `json:"confidence"`
+	Message    string  `json:"message"`
+	Suggestion string  `json:"suggestion"`
+}
+
+type ReviewReport struct {
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"-"`
+	// Dropped counts comments whose anchors did not match the diff.
+	Dropped int `json:"dropped"`
+}
+
+func (a *ReviewAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	if ctx.Diff == "" {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No diff available"})
+		return &res, nil
+	}
+
+	files, err := patch.Parse(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(`Review the following code change. Comment only on lines added or modified by the diff: line and end_line must be lines starting with "+".
+
+Diff:
+%s
+
+Surrounding file context (line numbers refer to the new version of each file):
+%s
+
+Respond JSON with { "summary": "", "comments": [{"file":"","line":0,"end_line":0,"hunk":"@@ -a,b +c,d @@","category":"bug|security|performance|maintainability|style|testing|docs","severity":"blocking|warning|info","confidence":0.0,"message":"","suggestion":"replacement text for lines line..end_line, or empty"}] }`,
+		ctx.Diff, reviewFileContext(ctx, files))
+	response, err := p.Complete(prompt, reviewSystemPrompt, true)
+	if err != nil {
+		return nil, fmt.Errorf("review failed: %w", err)
+	}
+
+	var parsed struct {
+		Summary  string          `json:"summary"`
+		Comments []ReviewComment `json:"comments"`
+	}
+	report := ReviewReport{}
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		report.Summary = response
+	} else {
+		report.Summary = parsed.Summary
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+	var patches []AgentArtifact
+	for _, c := range parsed.Comments {
+		finding, ok := anchorReviewComment(c, files)
+		if !ok {
+			report.Dropped++
+			continue
+		}
+		report.Findings = append(report.Findings, finding)
+
+		if finding.Suggestion == "" {
+			continue
+		}
+		if content, err := readChangedFile(repoPath, ctx.HeadRef, finding.File); err == nil {
+			patches = append(patches, AgentArtifact{
+				Type:    "patch",
+				Path:    finding.File,
+				Rule:    finding.Rule,
+				Content: patch.ReplaceLines(finding.File, string(content), finding.Line, finding.EndLine, finding.Suggestion),
+			})
+		}
+	}
+
+	severity := a.FindingsSeverity(report.Findings)
+
+	tokensUsed := len(prompt)/4 + len(response)/4
+	res := a.CreateResult(AgentResult{
+		Data:       report,
+		Findings:   report.Findings,
+		Severity:   severity,
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+		Artifacts:  patches,
+	})
+	return &res, nil
+}
+
+// FindingsSeverity is the severity of the worst finding.
+func (*ReviewAgent) FindingsSeverity(findings []Finding) string {
+	severity := "info"
+	for _, f := range findings {
+		if f.Severity == "blocking" {
+			severity = "blocking"
+			break
+		}
+		if f.Severity == "warning" {
+			severity = "warning"
+		}
+	}
+	return severity
+}
+
+// anchorReviewComment validates a comment against the diff. The first and
+// last line of the range must be lines added by a single hunk of the named
+// file, and a hunk header, when given, must be that hunk. Comments failing
+// either check are hallucinated, or on unchanged code, and dropped.
+func anchorReviewComment(c ReviewComment, files []patch.FileDiff) (Finding, bool) {
+	file := strings.TrimPrefix(strings.TrimPrefix(c.File, "b/"), "./")
+	if c.EndLine < c.Line {
+		c.EndLine = c.Line
+	}
+	for _, f := range files {
+		if f.NewPath != file {
+			continue
+		}
+		for _, h := range f.Hunks {
+			added := hunkAddedLines(h)
+			if !added[c.Line] || !added[c.EndLine] {
+				continue
+			}
+			if c.Hunk != "" && !hunkHeaderMatches(c.Hunk, h) {
+				return Finding{}, false
+			}
+			category := strings.ToLower(c.Category)
+			if category == "" {
+				category = "general"
+			}
+			return Finding{
+				Rule:       "review/" + category,
+				Severity:   normalizeSeverity(c.Severity),
+				Message:    c.Message,
+				File:       file,
+				Line:       c.Line,
+				EndLine:    c.EndLine,
+				Confidence: c.Confidence,
+				Suggestion: c.Suggestion,
+			}, true
+		}
+		return Finding{}, false
+	}
+	return Finding{}, false
+}
+
+// hunkAddedLines returns the new-side numbers of the lines h adds.
+func hunkAddedLines(h patch.Hunk) map[int]bool {
+	added := make(map[int]bool)
+	n := h.NewStart
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case patch.Added:
+			added[n] = true
+			n++
+		case patch.Context:
+			n++
+		}
+	}
+	return added
+}
+
+func hunkHeaderMatches(header string, h patch.Hunk) bool {
+	want := fmt.Sprintf("+%d,%d", h.NewStart, h.NewLines)
+	if h.NewLines == 1 && !strings.Contains(header, want) {
+		want = fmt.Sprintf("+%d ", h.NewStart)
+	}
+	return strings.Contains(header, want)
+}
+
+// reviewFileContext renders the lines around each hunk of the new file
+// versions, numbered so the model can anchor its comments. Staged changes are
+// read from the index, so that the numbers match the diff.
+func reviewFileContext(ctx AgentContext, files []patch.FileDiff) string {
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+
+	var sb strings.Builder
+	for _, f := range files {
+		if f.NewPath == "" || sb.Len() > maxReviewContext {
+			continue
+		}
+		content, err := readChangedFile(repoPath, ctx.HeadRef, f.NewPath)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(content), "\n")
+
+		fmt.Fprintf(&sb, "=== %s\n", f.NewPath)
+		shownUpTo := 0
+		for _, h := range f.Hunks {
+			from := max(h.NewStart-reviewContextLines, shownUpTo+1, 1)
+			to := min(h.NewStart+h.NewLines-1+reviewContextLines, len(lines))
+			if from > shownUpTo+1 && shownUpTo > 0 {
+				sb.WriteString("   ...\n")
+			}
+			for n := from; n <= to; n++ {
+				fmt.Fprintf(&sb, "%5d| %s\n", n, lines[n-1])
+			}
+			if to > shownUpTo {
+				shownUpTo = to
+			}
+		}
+	}
+	return sb.String()
+}
+
+// normalizeSeverity maps the severities models commonly use onto ours.
+func normalizeSeverity(s string) string {
+	switch strings.ToLower(s) {
+	case "blocking", "critical", "high", "error":
+		return "blocking"
+	case "warning", "medium", "moderate":
+		return "warning"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// cancelWaitDelay bounds how long a cancelled command's output is waited for,
+// since processes it started may keep the pipes open after it is killed.
+const cancelWaitDelay = 2 * time.Second
+
+// checkoutRevision materialises ref in a temporary git worktree so agents can
+// build or test it without touching the user's checkout. An empty ref refers to
+// the working tree itself. The returned cleanup func removes the worktree; it
+// does not depend on runCtx, so it also works after a cancellation.
+func checkoutRevision(runCtx context.Context, repoPath, ref string) (string, func(), error) {
+	if ref == "" {
+		return repoPath, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "verifier-rev-")
+	if err != nil {
+		return "", nil, err
+	}
+	out, err := exec.CommandContext(runCtx, "git", "-C", repoPath, "worktree", "add", "--detach", dir, ref).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		// An interrupted add may have registered the worktree already.
+		_ = exec.Command("git", "-C", repoPath, "worktree", "prune").Run()
+		return "", nil, fmt.Errorf("git worktree add %s: %v: %s", ref, err, strings.TrimSpace(string(out)))
+	}
+
+	cleanup := func() {
+		_ = exec.Command("git", "-C", repoPath, "worktree", "remove", "--force", dir).Run()
+		os.RemoveAll(dir)
+	}
+	return dir, cleanup, nil
+}
+
+// checkoutChange materialises the content the change describes: ref as
+// checkoutRevision does, or an export of the index when ref is empty, so that
+// unstaged edits are not built or tested and the user's checkout is not
+// touched. The returned cleanup func removes the copy.
+func checkoutChange(runCtx context.Context, repoPath, ref string) (string, func(), error) {
+	if ref != "" {
+		return checkoutRevision(runCtx, repoPath, ref)
+	}
+
+	dir, err := os.MkdirTemp("", "verifier-index-")
+	if err != nil {
+		return "", nil, err
+	}
+	cmd := exec.CommandContext(runCtx, "git", "-C", repoPath, "checkout-index", "--all", "--prefix="+dir+string(filepath.Separator))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("git checkout-index: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// readChangedFile returns the content of path as the change has it: at ref,
+// or staged in the index when ref is empty. Unlike readFileAtRevision it
+// ignores unstaged edits, so line numbers match the staged diff.
+func readChangedFile(repoPath, ref, path string) ([]byte, error) {
+	if ref != "" {
+		return readFileAtRevision(repoPath, ref, path)
+	}
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	content, exists, err := patch.IndexTarget{Repo: repo}.Read(filepath.ToSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+// readFileAtRevision returns the content of path at ref, or the working tree
+// copy when ref is empty. A file missing at that revision yields os.ErrNotExist.
+func readFileAtRevision(repoPath, ref, path string) ([]byte, error) {
+	if ref == "" {
+		return os.ReadFile(filepath.Join(repoPath, path))
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(filepath.ToSlash(path))
+	if err == object.ErrFileNotFound {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// listFilesAtRevision returns the paths of the files tracked at ref, or of the
+// tracked and untracked, non-ignored files in the working tree when ref is
+// empty.
+func listFilesAtRevision(repoPath, ref string) ([]string, error) {
+	if ref == "" {
+		out, err := exec.Command("git", "-C", repoPath, "ls-files", "-z", "--cached", "--others", "--exclude-standard").Output()
+		if err != nil {
+			return nil, fmt.Errorf("git ls-files: %w", err)
+		}
+		return strings.FieldsFunc(string(out), func(r rune) bool { return r == 0 }), nil
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = iter.ForEach(func(f *object.File) error {
+		files = append(files, f.Name)
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a repository holding files, committed, and returns its path.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "-q")
+	for name, content := range files {
+		writeFile(t, dir, name, content)
+	}
+	gitCmd(t, dir, "add", "-A")
+	gitCmd(t, dir, "commit", "-q", "-m", "init")
+	return dir
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckoutChangeExportsIndex(t *testing.T) {
+	repo := gitRepo(t, map[string]string{"a.txt": "committed\n", "sub/b.txt": "b\n"})
+	writeFile(t, repo, "a.txt", "staged\n")
+	gitCmd(t, repo, "add", "a.txt")
+	writeFile(t, repo, "a.txt", "unstaged\n")
+	writeFile(t, repo, "untracked.txt", "x\n")
+
+	dir, cleanup, err := checkoutChange(context.Background(), repo, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir == repo {
+		t.Fatal("checkoutChange returned the working tree")
+	}
+	for name, want := range map[string]string{"a.txt": "staged\n", "sub/b.txt": "b\n"} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "untracked.txt")); !os.IsNotExist(err) {
+		t.Errorf("untracked file exported: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "a.txt")); string(got) != "unstaged\n" {
+		t.Errorf("working tree a.txt = %q, want it untouched", got)
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cleanup left %s: %v", dir, err)
+	}
+}
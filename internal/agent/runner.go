@@ -1,26 +1,37 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
-	
+
 	"github.com/autodevopsai/verifier-go/internal/config"
 	"github.com/autodevopsai/verifier-go/internal/storage"
 )
 
 type AgentRunner struct {
-	cfg    *config.Config
+	cfg     *config.Config
 	metrics *storage.MetricsStore
 }
 
 func NewAgentRunner(cfg *config.Config) *AgentRunner {
 	return &AgentRunner{
-		cfg:    cfg,
+		cfg:     cfg,
 		metrics: storage.NewMetricsStore(),
 	}
 }
 
 func (r *AgentRunner) RunAgent(id string, ctx AgentContext) (*AgentResult, error) {
+	if !r.cfg.AgentEnabled(id) {
+		return &AgentResult{
+			AgentID:   id,
+			Status:    "skipped",
+			Error:     "Agent is disabled in config",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}, nil
+	}
+
 	// Check budget before running
 	todaysMetrics, _ := r.metrics.GetMetrics(24 * time.Hour)
 	tokensUsedToday := 0
@@ -36,13 +47,30 @@ func (r *AgentRunner) RunAgent(id string, ctx AgentContext) (*AgentResult, error
 		}, nil
 	}
 
-	agent, err := GetAgent(id, r.cfg)
+	cfg := configFor(id, r.cfg)
+	agent, err := GetAgent(id, cfg)
 	if err != nil {
 		return nil, err
 	}
+	if problems := ValidateAgentSettings(id, r.cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	settings := r.cfg.Agents[id]
+	ctx, ok := filterContext(ctx, settings)
+	if !ok {
+		return &AgentResult{
+			AgentID:   id,
+			Status:    "skipped",
+			Error:     "No changed files match the agent's include and exclude globs",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}, nil
+	}
+
+	ctx.NewCode = settings.NewCode
 
 	start := time.Now()
-	result, err := agent.Execute(ctx)
+	result, err := executeWithTimeout(agent, ctx, settings.Timeout)
 	duration := time.Since(start)
 
 	if err != nil {
@@ -52,6 +80,8 @@ func (r *AgentRunner) RunAgent(id string, ctx AgentContext) (*AgentResult, error
 			Error:     err.Error(),
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
+	} else {
+		filterNewCode(agent, result, ctx, ctx.NewCode)
 	}
 
 	// Record metrics
@@ -66,3 +96,45 @@ func (r *AgentRunner) RunAgent(id string, ctx AgentContext) (*AgentResult, error
 
 	return result, nil
 }
+
+// executeWithTimeout runs the agent, giving up after timeout when it is set.
+// A ContextAgent is cancelled and waited for, so its worktrees and stashed
+// changes are cleaned up before the timeout is reported. Other agents only
+// read the repository; they keep running in the background and the CLI exits
+// soon after.
+func executeWithTimeout(agent Agent, ctx AgentContext, timeout string) (*AgentResult, error) {
+	if timeout == "" {
+		return agent.Execute(ctx)
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if ca, ok := agent.(ContextAgent); ok {
+		runCtx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+		result, err := ca.ExecuteContext(runCtx, ctx)
+		// A cancelled agent may still return a partial result; drop it.
+		if runCtx.Err() != nil {
+			return nil, fmt.Errorf("agent timed out after %s", d)
+		}
+		return result, err
+	}
+
+	type outcome struct {
+		result *AgentResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := agent.Execute(ctx)
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-time.After(d):
+		return nil, fmt.Errorf("agent timed out after %s", d)
+	}
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// slowAgent blocks until it is cancelled and records whether its cleanup ran.
+type slowAgent struct {
+	BaseAgent
+	cleanedUp bool
+}
+
+func (a *slowAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	return a.ExecuteContext(context.Background(), ctx)
+}
+
+func (a *slowAgent) ExecuteContext(runCtx context.Context, _ AgentContext) (*AgentResult, error) {
+	defer func() { a.cleanedUp = true }()
+	<-runCtx.Done()
+	res := a.CreateResult(AgentResult{Status: "success"})
+	return &res, nil
+}
+
+func TestExecuteWithTimeoutCancelsContextAgent(t *testing.T) {
+	agent := &slowAgent{BaseAgent: BaseAgent{id: "slow"}}
+	result, err := executeWithTimeout(agent, AgentContext{}, "10ms")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Fatalf("executeWithTimeout() = %v, %v, want a timeout error", result, err)
+	}
+	if !agent.cleanedUp {
+		t.Error("timeout was reported before the agent returned")
+	}
+}
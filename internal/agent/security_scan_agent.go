@@ -3,12 +3,15 @@ package agent
 import (
 	"encoding/json"
 	"fmt"
-	"time"
-	
+	"strings"
+	"unicode"
+
 	"github.com/autodevopsai/verifier-go/internal/config"
 	"github.com/autodevopsai/verifier-go/internal/provider"
 )
 
+const securityScanSystemPrompt = "You are a security expert analyzing code for vulnerabilities. Be thorough but avoid false positives."
+
 type SecurityScanAgent struct {
 	BaseAgent
 	cfg *config.Config
@@ -25,6 +28,57 @@ func NewSecurityScanAgent(cfg *config.Config) Agent {
 	}
 }
 
+func (a *SecurityScanAgent) Details() AgentDetails {
+	opts, _ := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(SecurityScanOptions))
+	return AgentDetails{Prompts: map[string]string{"system": opts.systemPrompt()}}
+}
+
+// SecurityScanOptions configures the security-scan agent under
+// agents.security-scan.options.
+type SecurityScanOptions struct {
+	// SystemPrompt replaces the built-in system prompt when set, e.g. to
+	// describe the project's threat model.
+	SystemPrompt string `mapstructure:"system_prompt" yaml:"system_prompt"`
+	// BlockingSeverities are the vulnerability severities that block.
+	BlockingSeverities []string `mapstructure:"blocking_severities" yaml:"blocking_severities"`
+}
+
+func (o SecurityScanOptions) Validate() error {
+	for _, s := range o.BlockingSeverities {
+		switch s {
+		case "critical", "high", "medium", "low":
+		default:
+			return fmt.Errorf("blocking_severities: %q is not critical, high, medium or low", s)
+		}
+	}
+	return nil
+}
+
+func (*SecurityScanAgent) DefaultOptions() any {
+	return SecurityScanOptions{BlockingSeverities: []string{"critical", "high"}}
+}
+
+func (o SecurityScanOptions) systemPrompt() string {
+	if strings.TrimSpace(o.SystemPrompt) == "" {
+		return securityScanSystemPrompt
+	}
+	return o.SystemPrompt
+}
+
+// DefaultThresholds falls back to thresholds.security_risk. A risk score
+// above risk_score warns.
+func (a *SecurityScanAgent) DefaultThresholds() map[string]float64 {
+	return map[string]float64{"risk_score": float64(a.securityRiskDefault())}
+}
+
+func (a *SecurityScanAgent) securityRiskDefault() int {
+	if a.cfg.Thresholds.SecurityRisk == 0 {
+		return 5
+	}
+	return a.cfg.Thresholds.SecurityRisk
+}
+
+// SecurityAnalysis is the response requested from the model.
 type SecurityAnalysis struct {
 	RiskScore       int             `json:"risk_score"`
 	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
@@ -32,11 +86,68 @@ type SecurityAnalysis struct {
 }
 
 type Vulnerability struct {
-	Type           string `json:"type"`
-	Severity       string `json:"severity"`
-	Description    string `json:"description"`
-	Location       string `json:"location"`
-	Recommendation string `json:"recommendation"`
+	Type           string  `json:"type"`
+	Severity       string  `json:"severity"`
+	Description    string  `json:"description"`
+	Location       string  `json:"location"`
+	Recommendation string  `json:"recommendation"`
+	File           string  `json:"file,omitempty"`
+	Line           int     `json:"line,omitempty"`
+	CWE            string  `json:"cwe,omitempty"`
+	Confidence     float64 `json:"confidence,omitempty"`
+	// Patch is an optional unified diff that fixes the vulnerability.
+	Patch string `json:"patch,omitempty"`
+}
+
+// SecurityReport is the data of a security-scan result. The vulnerabilities
+// are reported as findings.
+type SecurityReport struct {
+	RiskScore int    `json:"risk_score"`
+	Summary   string `json:"summary"`
+}
+
+// finding converts v into a finding. Vulnerabilities with one of the
+// blocking severities block, other critical, high and medium ones warn and
+// low ones are informational.
+func (v Vulnerability) finding(blocking []string) Finding {
+	f := Finding{
+		Rule:        "security/" + ruleSlug(v.Type),
+		Severity:    "info",
+		Message:     v.Description,
+		File:        v.File,
+		Line:        v.Line,
+		Confidence:  v.Confidence,
+		Remediation: v.Recommendation,
+		Tags:        []string{v.Severity},
+	}
+	switch {
+	case containsString(blocking, v.Severity):
+		f.Severity = "blocking"
+	case v.Severity == "critical" || v.Severity == "high" || v.Severity == "medium":
+		f.Severity = "warning"
+	}
+	if f.Message == "" {
+		f.Message = v.Type
+	}
+	if v.Location != "" && v.Line == 0 {
+		f.Message += " (" + v.Location + ")"
+	}
+	if v.CWE != "" {
+		f.CWE = []string{v.CWE}
+	}
+	return f
+}
+
+// ruleSlug lowercases s and joins its words with dashes, e.g. "SQL
+// Injection" becomes "sql-injection".
+func ruleSlug(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "unknown"
+	}
+	return strings.Join(words, "-")
 }
 
 func (a *SecurityScanAgent) Execute(ctx AgentContext) (*AgentResult, error) {
@@ -45,19 +156,21 @@ func (a *SecurityScanAgent) Execute(ctx AgentContext) (*AgentResult, error) {
 		return &res, nil
 	}
 
+	opts, err := agentOptions(a.cfg, a.ID(), a.DefaultOptions().(SecurityScanOptions))
+	if err != nil {
+		return nil, err
+	}
 	p, err := provider.ProviderFactory(a.Model(), a.cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	prompt := fmt.Sprintf("Analyze the following code diff for security vulnerabilities.\n\n%s\n\nRespond JSON with { \"risk_score\": 0, \"vulnerabilities\": [{\"type\":\"\",\"severity\":\"critical|high|medium|low\",\"description\":\"\",\"location\":\"\",\"recommendation\":\"\"}], \"summary\":\"\" }", ctx.Diff)
-	systemPrompt := "You are a security expert analyzing code for vulnerabilities. Be thorough but avoid false positives."
-
-	response, err := p.Complete(prompt, systemPrompt, true)
+	prompt := fmt.Sprintf("Analyze the following code diff for security vulnerabilities.\n\n%s\n\nRespond JSON with { \"risk_score\": 0, \"vulnerabilities\": [{\"type\":\"\",\"severity\":\"critical|high|medium|low\",\"description\":\"\",\"location\":\"\",\"recommendation\":\"\",\"file\":\"path/of/file\",\"line\":0,\"cwe\":\"CWE-89\",\"confidence\":0.9,\"patch\":\"optional unified diff against the new file that fixes the issue\"}], \"summary\":\"\" }", ctx.Diff)
+	response, err := p.Complete(prompt, opts.systemPrompt(), true)
 	if err != nil {
 		return nil, fmt.Errorf("security scan failed: %w", err)
 	}
-	
+
 	var analysis SecurityAnalysis
 	if err := json.Unmarshal([]byte(response), &analysis); err != nil {
 		// If JSON fails, treat the whole response as a summary
@@ -65,30 +178,40 @@ func (a *SecurityScanAgent) Execute(ctx AgentContext) (*AgentResult, error) {
 		analysis.Summary = response
 	}
 
-	hasBlocking := false
+	var findings []Finding
+	severity := "info"
 	for _, v := range analysis.Vulnerabilities {
-		if v.Severity == "critical" || v.Severity == "high" {
-			hasBlocking = true
-			break
+		f := v.finding(opts.BlockingSeverities)
+		findings = append(findings, f)
+		switch {
+		case f.Severity == "blocking":
+			severity = "blocking"
+		case f.Severity == "warning" && severity == "info":
+			severity = "warning"
 		}
 	}
-	
-	severity := "info"
-	if hasBlocking {
-		severity = "blocking"
-	} else if analysis.RiskScore > 5 {
+	if severity == "info" && float64(analysis.RiskScore) > agentThreshold(a.cfg, a.ID(), "risk_score", float64(a.securityRiskDefault())) {
 		severity = "warning"
 	}
 
 	// This is a rough estimation. A real implementation would get this from the provider's response.
 	tokensUsed := len(prompt)/4 + len(response)/4
-	
+
+	var patches []AgentArtifact
+	for _, v := range analysis.Vulnerabilities {
+		if v.Patch != "" && v.File != "" {
+			patches = append(patches, AgentArtifact{Type: "patch", Path: v.File, Rule: "security/" + ruleSlug(v.Type), Content: v.Patch})
+		}
+	}
+
 	res := a.CreateResult(AgentResult{
 		Score:      analysis.RiskScore,
-		Data:       analysis,
+		Data:       SecurityReport{RiskScore: analysis.RiskScore, Summary: analysis.Summary},
+		Findings:   findings,
 		Severity:   severity,
 		TokensUsed: tokensUsed,
 		Cost:       calculateCost(a.Model(), tokensUsed),
+		Artifacts:  patches,
 	})
 	return &res, nil
 }
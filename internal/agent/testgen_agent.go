@@ -0,0 +1,364 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/autodevopsai/verifier-go/internal/provider"
+)
+
+const testgenSystemPrompt = "You are a Go engineer writing focused, deterministic table-driven unit tests."
+
+type TestgenAgent struct {
+	BaseAgent
+	cfg *config.Config
+}
+
+func NewTestgenAgent(cfg *config.Config) Agent {
+	return &TestgenAgent{
+		BaseAgent: BaseAgent{
+			id:          "testgen",
+			description: "Generates table-driven tests for changed Go functions",
+			model:       cfg.Models.Primary,
+		},
+		cfg: cfg,
+	}
+}
+
+func (*TestgenAgent) Details() AgentDetails {
+	return AgentDetails{Languages: []string{"Go"}, Prompts: map[string]string{"system": testgenSystemPrompt}}
+}
+
+// maxTestgenFunctions caps how many functions one run writes tests for.
+const maxTestgenFunctions = 10
+
+type GeneratedTest struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	TestFile string `json:"test_file,omitempty"`
+	Kept     bool   `json:"kept"`
+	Error    string `json:"error,omitempty"`
+}
+
+type TestgenReport struct {
+	Tests []GeneratedTest `json:"tests"`
+}
+
+// changedFunc is a function declaration touched by the diff.
+type changedFunc struct {
+	name    string // "Sub" or "(*T).Method"
+	file    string
+	pkgName string
+	source  string
+	imports string
+}
+
+func (a *TestgenAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	return a.ExecuteContext(context.Background(), ctx)
+}
+
+func (a *TestgenAgent) ExecuteContext(runCtx context.Context, ctx AgentContext) (*AgentResult, error) {
+	changed, err := patch.ChangedLines(ctx.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+	headDir, cleanup, err := checkoutChange(runCtx, repoPath, ctx.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	funcs := changedGoFuncs(headDir, changed)
+	if len(funcs) == 0 {
+		res := a.CreateResult(AgentResult{Status: "skipped", Error: "No changed Go functions"})
+		return &res, nil
+	}
+	if len(funcs) > maxTestgenFunctions {
+		funcs = funcs[:maxTestgenFunctions]
+	}
+
+	p, err := provider.ProviderFactory(a.Model(), a.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch, err := os.MkdirTemp("", "verifier-testgen-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+
+	var report TestgenReport
+	var patches []AgentArtifact
+	tokensUsed := 0
+	for i, fn := range funcs {
+		if err := runCtx.Err(); err != nil {
+			return nil, err
+		}
+		gt := GeneratedTest{Function: fn.name, File: fn.file}
+		testPath := testFileFor(headDir, fn)
+		if testPath == "" {
+			gt.Error = "no free test file name"
+			report.Tests = append(report.Tests, gt)
+			continue
+		}
+
+		prompt := fmt.Sprintf(`Write table-driven Go tests for the function below.
+
+Package: %s
+File: %s
+Imports in that file:
+%s
+
+Function:
+%s
+
+Existing test functions in the package (do not redeclare them): %s
+
+Reply with a complete Go test file in package %s that uses only the standard library testing package and compiles on its own. Reply with the code only.`,
+			fn.pkgName, fn.file, fn.imports, fn.source, strings.Join(existingTests(headDir, filepath.Dir(fn.file)), ", "), fn.pkgName)
+		var source, runErr string
+		// One retry lets the model correct a test that does not build or pass.
+		for attempt := 0; attempt < 2; attempt++ {
+			response, err := p.Complete(prompt, testgenSystemPrompt, false)
+			if err != nil {
+				runErr = err.Error()
+				break
+			}
+			tokensUsed += len(prompt)/4 + len(response)/4
+			source = stripCodeFence(response)
+
+			scratchFile := filepath.Join(scratch, fmt.Sprintf("gen%d_test.go", i))
+			if err := os.WriteFile(scratchFile, []byte(source), 0644); err != nil {
+				return nil, err
+			}
+			runErr = runGeneratedTest(runCtx, headDir, filepath.Join(headDir, testPath), scratchFile, source)
+			if runErr == "" {
+				break
+			}
+			prompt += fmt.Sprintf("\n\nYour previous attempt:\n%s\n\nfailed with:\n%s\n\nFix it.", source, runErr)
+		}
+
+		if runErr != "" {
+			gt.Error = lastLines(runErr, 20)
+		} else {
+			gt.Kept = true
+			gt.TestFile = testPath
+			patches = append(patches, AgentArtifact{
+				Type:    "patch",
+				Path:    testPath,
+				Rule:    "testgen",
+				Content: patch.NewFile(testPath, source),
+			})
+		}
+		report.Tests = append(report.Tests, gt)
+	}
+
+	res := a.CreateResult(AgentResult{
+		Data:       report,
+		Severity:   "info",
+		TokensUsed: tokensUsed,
+		Cost:       calculateCost(a.Model(), tokensUsed),
+		Artifacts:  patches,
+	})
+	return &res, nil
+}
+
+// changedGoFuncs finds the function declarations in non-test Go files that
+// contain a changed line.
+func changedGoFuncs(root string, changed map[string][]int) []changedFunc {
+	var files []string
+	for file := range changed {
+		if strings.HasSuffix(file, ".go") && !strings.HasSuffix(file, "_test.go") {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	var funcs []changedFunc
+	for _, file := range files {
+		src, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+		if err != nil || f.Name.Name == "main" {
+			continue
+		}
+
+		var imports []string
+		for _, imp := range f.Imports {
+			imports = append(imports, imp.Path.Value)
+		}
+
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil || fd.Name.Name == "init" {
+				continue
+			}
+			start, end := fset.Position(fd.Pos()).Line, fset.Position(fd.End()).Line
+			if !anyLineIn(changed[file], start, end) {
+				continue
+			}
+			funcs = append(funcs, changedFunc{
+				name:    funcDeclName(fd),
+				file:    file,
+				pkgName: f.Name.Name,
+				source:  string(src[fset.Position(fd.Pos()).Offset:fset.Position(fd.End()).Offset]),
+				imports: strings.Join(imports, "\n"),
+			})
+		}
+	}
+	return funcs
+}
+
+// funcDeclName renders a declaration name, qualifying methods with their
+// receiver type, e.g. "(*Store).Get".
+func funcDeclName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	recv := fd.Recv.List[0].Type
+	if idx, ok := recv.(*ast.IndexExpr); ok {
+		recv = idx.X
+	}
+	switch t := recv.(type) {
+	case *ast.StarExpr:
+		if id, ok := t.X.(*ast.Ident); ok {
+			return "(*" + id.Name + ")." + fd.Name.Name
+		}
+	case *ast.Ident:
+		return t.Name + "." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
+
+func anyLineIn(lines []int, start, end int) bool {
+	for _, n := range lines {
+		if n >= start && n <= end {
+			return true
+		}
+	}
+	return false
+}
+
+var nonIdent = regexp.MustCompile(`[^a-z0-9]+`)
+
+// testFileFor picks an unused _test.go name next to the function's file.
+func testFileFor(root string, fn changedFunc) string {
+	base := strings.TrimSuffix(filepath.Base(fn.file), ".go")
+	name := strings.Trim(nonIdent.ReplaceAllString(strings.ToLower(fn.name), "_"), "_")
+	for i := 0; i < 10; i++ {
+		candidate := fmt.Sprintf("%s_%s_test.go", base, name)
+		if i > 0 {
+			candidate = fmt.Sprintf("%s_%s_%d_test.go", base, name, i+1)
+		}
+		path := filepath.Join(filepath.Dir(fn.file), candidate)
+		if _, err := os.Stat(filepath.Join(root, path)); os.IsNotExist(err) {
+			return path
+		}
+	}
+	return ""
+}
+
+// existingTests lists the Test functions already declared in dir.
+func existingTests(root, dir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(root, dir, "*_test.go"))
+	var names []string
+	for _, m := range matches {
+		f, err := parser.ParseFile(token.NewFileSet(), m, nil, 0)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && strings.HasPrefix(fd.Name.Name, "Test") {
+				names = append(names, fd.Name.Name)
+			}
+		}
+	}
+	return names
+}
+
+// runGeneratedTest builds and runs the tests in source as if it were
+// installed at testPath, using an overlay so the checkout is left untouched.
+// It returns the failure output, or "" when the tests pass.
+func runGeneratedTest(runCtx context.Context, root, testPath, scratchFile, source string) string {
+	f, err := parser.ParseFile(token.NewFileSet(), testPath, source, 0)
+	if err != nil {
+		return err.Error()
+	}
+	var tests []string
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && strings.HasPrefix(fd.Name.Name, "Test") {
+			tests = append(tests, regexp.QuoteMeta(fd.Name.Name))
+		}
+	}
+	if len(tests) == 0 {
+		return "no Test functions in generated file"
+	}
+
+	// Overlay paths must be absolute.
+	absTest, err := filepath.Abs(testPath)
+	if err != nil {
+		return err.Error()
+	}
+	overlay, err := json.Marshal(map[string]map[string]string{"Replace": {absTest: scratchFile}})
+	if err != nil {
+		return err.Error()
+	}
+	overlayPath := scratchFile + ".overlay.json"
+	if err := os.WriteFile(overlayPath, overlay, 0644); err != nil {
+		return err.Error()
+	}
+
+	cmd := exec.CommandContext(runCtx, "go", "test", "-overlay", overlayPath, "-count=1", "-run", "^("+strings.Join(tests, "|")+")$", "./"+filepath.ToSlash(mustRel(root, filepath.Dir(testPath))))
+	cmd.Dir = root
+	cmd.WaitDelay = cancelWaitDelay
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output)
+	}
+	return ""
+}
+
+func mustRel(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return rel
+}
+
+// stripCodeFence removes a surrounding markdown code fence, if any.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s + "\n"
+	}
+	// Drop the opening fence line; a bare fence has nothing after it.
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	} else {
+		s = ""
+	}
+	s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	return strings.TrimSpace(s) + "\n"
+}
@@ -0,0 +1,21 @@
+package agent
+
+import "testing"
+
+func TestStripCodeFence(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"package x", "package x\n"},
+		{"```go\npackage x\n```", "package x\n"},
+		{"  ```\npackage x\n```\n", "package x\n"},
+		{"```", "\n"},
+		{"```go", "\n"},
+		{"```go\n```", "\n"},
+	}
+	for _, tt := range tests {
+		if got := stripCodeFence(tt.in); got != tt.want {
+			t.Errorf("stripCodeFence(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// WASM agents are WASI (preview 1) modules run in-process by wazero. They
+// speak the plugin protocol without the handshake: the module reads one
+// execute message from stdin and writes one result or error message to
+// stdout. The repository is mounted read-only at wasmRepoMount and is the
+// context's repo_path; the module has no network, no host environment and
+// no other files. Runs are bounded by a timeout and a memory cap, and the
+// guest is interrupted when the timeout expires. wazero has no fuel or
+// instruction metering, so the timeout is the only bound on CPU time.
+
+const (
+	wasmRepoMount        = "/repo"
+	defaultWasmMemoryMB  = 256
+	maxWasmMemoryMB      = 4096
+	maxWasmOutput        = 16 * 1024 * 1024
+	wasmPagesPerMegabyte = 16 // 64 KiB pages
+)
+
+// WasmSpec describes a WASM agent module and its limits.
+type WasmSpec struct {
+	ID          string
+	Module      string
+	Description string
+	Timeout     time.Duration
+	MemoryMB    int
+}
+
+type WasmAgent struct {
+	BaseAgent
+	spec WasmSpec
+}
+
+func NewWasmAgent(spec WasmSpec) Agent {
+	description := spec.Description
+	if description == "" {
+		description = "WASM agent " + filepath.Base(spec.Module)
+	}
+	return &WasmAgent{
+		BaseAgent: BaseAgent{id: spec.ID, description: description, model: "none"},
+		spec:      spec,
+	}
+}
+
+func (a *WasmAgent) Execute(ctx AgentContext) (*AgentResult, error) {
+	result, stderr, err := RunWasm(a.spec, ctx)
+	if err != nil {
+		if s := strings.TrimSpace(stderr); s != "" {
+			err = fmt.Errorf("%w\nstderr:\n%s", err, lastLines(s, 20))
+		}
+		return nil, err
+	}
+	if problems := ValidatePluginResult(result); len(problems) > 0 {
+		return nil, fmt.Errorf("wasm agent %s returned an invalid result: %s", a.ID(), strings.Join(problems, "; "))
+	}
+
+	res := a.CreateResult(*result)
+	if stderr != "" {
+		res.Artifacts = append(res.Artifacts, AgentArtifact{Type: "stderr", Content: stderr})
+	}
+	return &res, nil
+}
+
+// LoadWasmAgents registers the WASM agents declared in cfg.WasmAgents and
+// found in PluginDir. Like plugin and prompt agents they go through
+// registerExternal rather than Register: they land in the same registry, but
+// an ID clash with a built-in agent is a config error, not a panic, and they
+// are listed with their kind.
+func LoadWasmAgents(cfg *config.Config) error {
+	specs, err := DiscoverWasmAgents(cfg)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		spec := spec
+		if err := registerExternal("wasm", spec.ID, func(_ *config.Config) Agent { return NewWasmAgent(spec) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiscoverWasmAgents lists the modules declared in config and the .wasm files
+// in PluginDir, config taking precedence for the same ID.
+func DiscoverWasmAgents(cfg *config.Config) ([]WasmSpec, error) {
+	byID := make(map[string]WasmSpec)
+
+	files, err := filepath.Glob(filepath.Join(PluginDir, "*.wasm"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".wasm")
+		byID[id] = WasmSpec{ID: id, Module: file, Timeout: defaultPluginTimeout, MemoryMB: defaultWasmMemoryMB}
+	}
+
+	for id, w := range cfg.WasmAgents {
+		if w.Module == "" {
+			return nil, fmt.Errorf("wasm agent %s has no module", id)
+		}
+		spec := WasmSpec{ID: id, Module: w.Module, Description: w.Description, Timeout: defaultPluginTimeout, MemoryMB: defaultWasmMemoryMB}
+		if w.Timeout != "" {
+			d, err := time.ParseDuration(w.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("wasm agent %s: invalid timeout: %w", id, err)
+			}
+			spec.Timeout = d
+		}
+		if w.MemoryMB != 0 {
+			if w.MemoryMB < 0 || w.MemoryMB > maxWasmMemoryMB {
+				return nil, fmt.Errorf("wasm agent %s: memory_mb must be between 1 and %d", id, maxWasmMemoryMB)
+			}
+			spec.MemoryMB = w.MemoryMB
+		}
+		byID[id] = spec
+	}
+
+	specs := make([]WasmSpec, 0, len(byID))
+	for _, spec := range byID {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].ID < specs[j].ID })
+	return specs, nil
+}
+
+// RunWasm instantiates the module in a fresh runtime, sends ctx and decodes
+// the reply. It returns whatever the module wrote to stderr even on failure.
+func RunWasm(spec WasmSpec, ctx AgentContext) (*AgentResult, string, error) {
+	wasm, err := os.ReadFile(spec.Module)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read wasm module: %w", err)
+	}
+	repoPath := ctx.RepoPath
+	if repoPath == "" {
+		repoPath = "."
+	}
+	repoPath, err = filepath.Abs(repoPath)
+	if err != nil {
+		return nil, "", err
+	}
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+	memoryMB := spec.MemoryMB
+	if memoryMB <= 0 {
+		memoryMB = defaultWasmMemoryMB
+	}
+
+	guestCtx := ctx
+	guestCtx.RepoPath = wasmRepoMount
+	input, err := json.Marshal(PluginMessage{Type: "execute", ProtocolVersion: PluginProtocolVersion, Context: &guestCtx})
+	if err != nil {
+		return nil, "", err
+	}
+
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rt := wazero.NewRuntimeWithConfig(runCtx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(memoryMB*wasmPagesPerMegabyte)).
+		WithCloseOnContextDone(true))
+	defer rt.Close(context.Background())
+	wasi_snapshot_preview1.MustInstantiate(runCtx, rt)
+
+	compiled, err := rt.CompileModule(runCtx, wasm)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid wasm module %s: %w", spec.Module, err)
+	}
+
+	stdout := &cappedBuffer{limit: maxWasmOutput}
+	stderr := &tailBuffer{limit: maxPluginStderr}
+	// Real clocks and randomness rather than wazero's deterministic defaults,
+	// which would break timeouts and hashing in the guest.
+	modCfg := wazero.NewModuleConfig().
+		WithName(spec.ID).
+		WithArgs(spec.ID).
+		WithEnv("VERIFIER_PLUGIN_PROTOCOL", fmt.Sprint(PluginProtocolVersion)).
+		WithStdin(bytes.NewReader(append(input, '\n'))).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithFSConfig(wazero.NewFSConfig().WithReadOnlyDirMount(repoPath, wasmRepoMount)).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+
+	_, err = rt.InstantiateModule(runCtx, compiled, modCfg)
+	var exitErr *sys.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 0:
+	case runCtx.Err() != nil:
+		return nil, stderr.String(), fmt.Errorf("wasm agent %s timed out after %s", spec.ID, timeout)
+	case err != nil:
+		return nil, stderr.String(), fmt.Errorf("wasm agent %s failed: %w", spec.ID, err)
+	}
+	if stdout.overflow {
+		return nil, stderr.String(), fmt.Errorf("wasm agent %s wrote more than %d bytes to stdout", spec.ID, maxWasmOutput)
+	}
+
+	var reply PluginMessage
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.buf.Bytes()), &reply); err != nil {
+		return nil, stderr.String(), fmt.Errorf("wasm agent %s: invalid reply: %w", spec.ID, err)
+	}
+	switch {
+	case reply.Type == "error":
+		return nil, stderr.String(), fmt.Errorf("wasm agent %s reported: %s", spec.ID, reply.Error)
+	case reply.Type != "result" || reply.Result == nil:
+		return nil, stderr.String(), fmt.Errorf("wasm agent %s: expected a result message, got %q", spec.ID, reply.Type)
+	}
+	return reply.Result, stderr.String(), nil
+}
+
+// cappedBuffer is an io.Writer that fails once more than limit bytes have
+// been written to it.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if c.buf.Len()+len(p) > c.limit {
+		c.overflow = true
+		return 0, errors.New("output limit exceeded")
+	}
+	return c.buf.Write(p)
+}
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/agent"
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var agentsCmd = &cobra.Command{
+	Use:   "agents",
+	Short: "Inspect and manage agents",
+}
+
+var agentsFormat string
+
+var agentsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available agents",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadAgentsConfig()
+		if err != nil {
+			return err
+		}
+		var infos []agent.AgentInfo
+		for _, id := range agent.ListAgents() {
+			info, err := agent.DescribeAgent(id, cfg)
+			if err != nil {
+				return err
+			}
+			infos = append(infos, info)
+		}
+
+		switch agentsFormat {
+		case "json":
+			return printJSON(infos)
+		case "table":
+		default:
+			return fmt.Errorf("invalid format: %s", agentsFormat)
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Agent", "Kind", "Type", "Model", "Enabled", "Description")
+		for _, info := range infos {
+			table.Append([]string{info.ID, info.Kind, agentType(info), info.Model, yesNo(info.Enabled), info.Description})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var agentsDescribeCmd = &cobra.Command{
+	Use:   "describe [agent-id]",
+	Short: "Show an agent's configuration, prompts and supported languages",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadAgentsConfig()
+		if err != nil {
+			return err
+		}
+		if _, err := agent.GetAgent(args[0], cfg); err != nil {
+			return fmt.Errorf("%w. Available agents: %s", err, strings.Join(agent.ListAgents(), ", "))
+		}
+		info, err := agent.DescribeAgent(args[0], cfg)
+		if err != nil {
+			return err
+		}
+
+		switch agentsFormat {
+		case "json":
+			return printJSON(info)
+		case "table":
+		default:
+			return fmt.Errorf("invalid format: %s", agentsFormat)
+		}
+		fmt.Printf("%s: %s\n\n", info.ID, info.Description)
+		fmt.Printf("Kind:      %s\n", info.Kind)
+		fmt.Printf("Type:      %s\n", agentType(info))
+		fmt.Printf("Model:     %s\n", info.Model)
+		fmt.Printf("Enabled:   %s\n", yesNo(info.Enabled))
+		languages := "any"
+		if len(info.Languages) > 0 {
+			languages = strings.Join(info.Languages, ", ")
+		}
+		fmt.Printf("Languages: %s\n", languages)
+
+		if info.Timeout != "" {
+			fmt.Printf("Timeout:   %s\n", info.Timeout)
+		}
+		if len(info.Include) > 0 {
+			fmt.Printf("Include:   %s\n", strings.Join(info.Include, ", "))
+		}
+		if len(info.Exclude) > 0 {
+			fmt.Printf("Exclude:   %s\n", strings.Join(info.Exclude, ", "))
+		}
+		fmt.Printf("New code:  %s\n", info.NewCode)
+
+		if len(info.Thresholds) > 0 || len(info.Options) > 0 {
+			fmt.Println("\nConfig:")
+			table := tablewriter.NewWriter(os.Stdout)
+			table.Header("Key", "Type", "Value")
+			names := make([]string, 0, len(info.Thresholds))
+			for name := range info.Thresholds {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				table.Append([]string{"agents." + info.ID + ".thresholds." + name, "float64", fmt.Sprint(info.Thresholds[name])})
+			}
+			for _, f := range info.Options {
+				table.Append([]string{f.Key, f.Type, fmt.Sprint(f.Value)})
+			}
+			table.Render()
+		}
+
+		names := make([]string, 0, len(info.Prompts))
+		for name := range info.Prompts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("\nPrompt (%s):\n%s\n", name, strings.TrimSpace(info.Prompts[name]))
+		}
+		return nil
+	},
+}
+
+var agentsEnableCmd = &cobra.Command{
+	Use:   "enable [agent-id]",
+	Short: "Enable an agent in .verifier/config.yaml",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAgentEnabled(args[0], true)
+	},
+}
+
+var agentsDisableCmd = &cobra.Command{
+	Use:   "disable [agent-id]",
+	Short: "Disable an agent in .verifier/config.yaml so that runs and hooks skip it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAgentEnabled(args[0], false)
+	},
+}
+
+// loadAgentsConfig loads the config and registers the external agents, so the
+// agents commands see the same agents as run.
+func loadAgentsConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w. Please run 'verifier init'", err)
+	}
+	if err := agent.LoadAgents(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func setAgentEnabled(id string, enabled bool) error {
+	cfg, err := loadAgentsConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := agent.GetAgent(id, cfg); err != nil {
+		return fmt.Errorf("%w. Available agents: %s", err, strings.Join(agent.ListAgents(), ", "))
+	}
+	if err := config.SetAgentEnabled(id, enabled); err != nil {
+		return fmt.Errorf("failed to update configuration: %w", err)
+	}
+	if enabled {
+		fmt.Printf("✓ Enabled %s\n", id)
+	} else {
+		fmt.Printf("✓ Disabled %s\n", id)
+	}
+	return nil
+}
+
+func agentType(info agent.AgentInfo) string {
+	if info.LLM {
+		return "LLM"
+	}
+	return "deterministic"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+var agentsValidateCmd = &cobra.Command{
+	Use:   "validate [plugin]",
+	Short: "Check that a plugin agent speaks the plugin protocol",
+	Long: fmt.Sprintf(`Run a plugin agent against the staged changes and check each step of the
+plugin protocol (version %d): the handshake, the result message and the
+result itself. The plugin is an ID from .verifier/agents/ or the plugins
+config, or a path to an executable.`, agent.PluginProtocolVersion),
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := resolvePlugin(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx, err := collectContext("")
+		if err != nil {
+			fmt.Printf("Warning: could not collect git context, validating with an empty change: %v\n", err)
+			ctx.RepoPath = "."
+		}
+
+		fmt.Printf("Validating plugin %s (%s)...\n", spec.ID, spec.Command)
+		run, err := agent.RunPlugin(spec, ctx)
+		failed := false
+		check := func(ok bool, msg string) {
+			if ok {
+				fmt.Printf("  ✓ %s\n", msg)
+			} else {
+				fmt.Printf("  ✗ %s\n", msg)
+				failed = true
+			}
+		}
+
+		if run.Handshake != nil {
+			h := run.Handshake
+			check(true, fmt.Sprintf("handshake: protocol version %d", h.ProtocolVersion))
+			if h.ID != "" && h.ID != spec.ID {
+				fmt.Printf("  ! handshake id %q differs from the plugin id %q\n", h.ID, spec.ID)
+			}
+			if h.Description == "" {
+				fmt.Println("  ! handshake has no description")
+			}
+		}
+		if err != nil {
+			check(false, err.Error())
+		} else {
+			check(true, fmt.Sprintf("result received in %s", run.Duration.Round(1e6)))
+			problems := agent.ValidatePluginResult(run.Result)
+			for _, p := range problems {
+				check(false, "result: "+p)
+			}
+			if len(problems) == 0 {
+				check(true, fmt.Sprintf("result is valid: status %s, %d findings", run.Result.Status, len(run.Result.Findings)))
+			}
+		}
+		if s := strings.TrimSpace(run.Stderr); s != "" && err == nil {
+			fmt.Printf("stderr:\n%s\n", s)
+		}
+
+		if failed {
+			return fmt.Errorf("plugin %s does not conform to the plugin protocol", spec.ID)
+		}
+		return nil
+	},
+}
+
+// resolvePlugin finds a configured or discovered plugin by ID, or treats name
+// as the path of an executable.
+func resolvePlugin(name string) (agent.PluginSpec, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	specs, err := agent.DiscoverPlugins(cfg)
+	if err != nil {
+		return agent.PluginSpec{}, err
+	}
+	for _, spec := range specs {
+		if spec.ID == name {
+			return spec, nil
+		}
+	}
+
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return agent.PluginSpec{}, err
+		}
+		base := filepath.Base(name)
+		return agent.PluginSpec{ID: strings.TrimSuffix(base, filepath.Ext(base)), Command: abs}, nil
+	}
+	return agent.PluginSpec{}, fmt.Errorf("plugin not found: %s", name)
+}
+
+func init() {
+	for _, c := range []*cobra.Command{agentsListCmd, agentsDescribeCmd} {
+		c.Flags().StringVarP(&agentsFormat, "format", "f", "table", "Output format (table|json)")
+	}
+	agentsCmd.AddCommand(agentsListCmd, agentsDescribeCmd, agentsEnableCmd, agentsDisableCmd, agentsValidateCmd)
+	rootCmd.AddCommand(agentsCmd)
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/autodevopsai/verifier-go/internal/storage"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var complexityDays int
+var complexityFile string
+var complexityFormat string
+
+var complexityCmd = &cobra.Command{
+	Use:   "complexity",
+	Short: "Inspect the function complexity recorded by the complexity agent",
+}
+
+var complexityHistoryCmd = &cobra.Command{
+	Use:   "history [function]",
+	Short: "Show complexity history per function, or the hotspots",
+	Long: `Show the complexity samples the complexity agent recorded for a function,
+e.g. "Parse" or "(*Store).Get", oldest first. Without a function, list every
+recorded function with its latest complexity and the change since its first
+sample in the period, most complex first.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if complexityDays <= 0 {
+			return fmt.Errorf("invalid days: %d", complexityDays)
+		}
+		switch complexityFormat {
+		case "table", "json":
+		default:
+			return fmt.Errorf("invalid format: %s", complexityFormat)
+		}
+		function := ""
+		if len(args) > 0 {
+			function = args[0]
+		}
+
+		store := storage.NewMetricsStore()
+		samples, err := store.GetComplexityHistory(time.Duration(complexityDays)*24*time.Hour, complexityFile, function)
+		if err != nil {
+			return err
+		}
+		if function != "" {
+			return printComplexityHistory(samples)
+		}
+		return printComplexityHotspots(samples)
+	},
+}
+
+func printComplexityHistory(samples []storage.FunctionMetric) error {
+	if complexityFormat == "json" {
+		return printJSON(samples)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Time", "Revision", "File", "Function", "Cyclomatic", "Cognitive", "Length", "Nesting", "Params")
+	for _, s := range samples {
+		table.Append([]string{
+			s.Timestamp.Format("2006-01-02 15:04"),
+			shortRevision(s.Revision),
+			s.File,
+			s.Function,
+			strconv.Itoa(s.Cyclomatic),
+			strconv.Itoa(s.Cognitive),
+			strconv.Itoa(s.Length),
+			strconv.Itoa(s.Nesting),
+			strconv.Itoa(s.Params),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+// complexityHotspot is the latest sample of a function and how its
+// complexity changed since the first sample in the period.
+type complexityHotspot struct {
+	storage.FunctionMetric
+	Samples         int `json:"samples"`
+	CyclomaticDelta int `json:"cyclomatic_delta"`
+	CognitiveDelta  int `json:"cognitive_delta"`
+}
+
+func printComplexityHotspots(samples []storage.FunctionMetric) error {
+	// Samples are oldest first, so the first one seen per function is the
+	// baseline and the last one its current complexity.
+	first := make(map[string]storage.FunctionMetric)
+	byFunc := make(map[string]*complexityHotspot)
+	for _, s := range samples {
+		key := s.File + "\x00" + s.Function
+		if _, ok := first[key]; !ok {
+			first[key] = s
+			byFunc[key] = &complexityHotspot{}
+		}
+		h := byFunc[key]
+		h.FunctionMetric = s
+		h.Samples++
+		h.CyclomaticDelta = s.Cyclomatic - first[key].Cyclomatic
+		h.CognitiveDelta = s.Cognitive - first[key].Cognitive
+	}
+
+	var hotspots []complexityHotspot
+	for _, h := range byFunc {
+		hotspots = append(hotspots, *h)
+	}
+	sort.Slice(hotspots, func(i, j int) bool {
+		a, b := hotspots[i], hotspots[j]
+		if a.Cognitive != b.Cognitive {
+			return a.Cognitive > b.Cognitive
+		}
+		if a.Cyclomatic != b.Cyclomatic {
+			return a.Cyclomatic > b.Cyclomatic
+		}
+		return a.File+a.Function < b.File+b.Function
+	})
+
+	if complexityFormat == "json" {
+		return printJSON(hotspots)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("File", "Function", "Cognitive", "Δ", "Cyclomatic", "Δ", "Samples", "Last seen")
+	for _, h := range hotspots {
+		table.Append([]string{
+			h.File,
+			h.Function,
+			strconv.Itoa(h.Cognitive),
+			fmt.Sprintf("%+d", h.CognitiveDelta),
+			strconv.Itoa(h.Cyclomatic),
+			fmt.Sprintf("%+d", h.CyclomaticDelta),
+			strconv.Itoa(h.Samples),
+			h.Timestamp.Format("2006-01-02"),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func printJSON(v any) error {
+	output, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+func shortRevision(rev string) string {
+	if len(rev) > 12 {
+		return rev[:12]
+	}
+	return rev
+}
+
+func init() {
+	complexityHistoryCmd.Flags().IntVar(&complexityDays, "days", 90, "How many days of history to show")
+	complexityHistoryCmd.Flags().StringVar(&complexityFile, "file", "", "Only show functions in this file")
+	complexityHistoryCmd.Flags().StringVarP(&complexityFormat, "format", "f", "table", "Output format (table|json)")
+	complexityCmd.AddCommand(complexityHistoryCmd)
+	rootCmd.AddCommand(complexityCmd)
+}
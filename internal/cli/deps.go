@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/autodevopsai/verifier-go/internal/osv"
+	"github.com/spf13/cobra"
+)
+
+var depsCmd = &cobra.Command{
+	Use:   "deps",
+	Short: "Manage the offline dependency vulnerability database",
+}
+
+var depsUpdateDBCmd = &cobra.Command{
+	Use:   "update-db [osv-export.zip...]",
+	Short: "Import OSV zip exports into the local vulnerability database",
+	Long: `Import one or more OSV zip exports (for example the per-ecosystem all.zip
+files published by osv.dev) into .verifier/osv. No network access is needed,
+so the exports can be mirrored into air-gapped environments.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db := osv.NewDatabase()
+		for _, path := range args {
+			n, err := db.Import(path)
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", path, err)
+			}
+			fmt.Printf("✓ Imported %d vulnerabilities from %s\n", n, path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	depsCmd.AddCommand(depsUpdateDBCmd)
+	rootCmd.AddCommand(depsCmd)
+}
@@ -6,7 +6,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/autodevopsai/verifier-go/internal/agent"
 	"github.com/autodevopsai/verifier-go/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -42,6 +44,17 @@ var doctorCmd = &cobra.Command{
 		if err == nil {
 			keyOk := cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.Anthropic.APIKey != ""
 			checks = append(checks, Check{"Provider API key", keyOk, ""})
+
+			// External agents and per-agent settings
+			if err := agent.LoadAgents(cfg); err != nil {
+				checks = append(checks, Check{"External agents", false, err.Error()})
+			}
+			problems := agent.ValidateAgentsConfig(cfg)
+			msg := fmt.Sprintf("%d agents configured", len(cfg.Agents))
+			if len(problems) > 0 {
+				msg = strings.Join(problems, "\n   ")
+			}
+			checks = append(checks, Check{"Agent settings", len(problems) == 0, msg})
 		}
 
 		// Git binary
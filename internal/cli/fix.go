@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/agent"
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/autodevopsai/verifier-go/internal/patch"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+)
+
+var fixYes bool
+var fixIndex bool
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [agent-id...]",
+	Short: "Preview and apply the patches suggested by agents",
+	Long: `Run the given agents on the staged changes and offer each patch they
+suggest. Agents see the staged content, so accepted patches are applied to the
+index, and then to the working tree unless --index is set, and the agent is
+run again to confirm the finding is gone. A patch that does not apply cleanly
+to the index is rolled back; one that conflicts with unstaged changes stays in
+the index only.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w. Please run 'verifier init'", err)
+		}
+		if err := agent.LoadAgents(cfg); err != nil {
+			return err
+		}
+		for _, id := range args {
+			if _, err := agent.GetAgent(id, cfg); err != nil {
+				return fmt.Errorf("%w. Available agents: %s", err, strings.Join(agent.ListAgents(), ", "))
+			}
+		}
+
+		ctx, err := collectContext("")
+		if err != nil {
+			return fmt.Errorf("could not collect git context: %w", err)
+		}
+
+		repo, err := git.PlainOpen(ctx.RepoPath)
+		if err != nil {
+			return fmt.Errorf("failed to open git repository: %w", err)
+		}
+		index := patch.IndexTarget{Repo: repo}
+		worktree := patch.WorktreeTarget{Root: ctx.RepoPath}
+
+		runner := agent.NewAgentRunner(cfg)
+		reader := bufio.NewReader(os.Stdin)
+		failed := 0
+		for _, id := range args {
+			result, err := runner.RunAgent(id, ctx)
+			if err != nil {
+				return fmt.Errorf("agent %s failed: %w", id, err)
+			}
+
+			var applied []agent.AgentArtifact
+			for _, artifact := range result.Artifacts {
+				if artifact.Type != "patch" {
+					continue
+				}
+				fmt.Printf("\n── %s: %s in %s\n%s\n", id, artifact.Rule, artifact.Path, artifact.Content)
+				if err := checkPatchFiles(artifact); err != nil {
+					fmt.Printf("❌ Skipping patch: %v\n", err)
+					failed++
+					continue
+				}
+				if !fixYes && !confirm(reader, "Apply this patch?") {
+					continue
+				}
+				if _, err := patch.ApplyTo(index, artifact.Content); err != nil {
+					fmt.Printf("❌ Could not apply patch, changes rolled back: %v\n", err)
+					failed++
+					continue
+				}
+				applied = append(applied, artifact)
+				if fixIndex {
+					fmt.Println("✓ Applied to the index")
+					continue
+				}
+				if _, err := patch.ApplyTo(worktree, artifact.Content); err != nil {
+					fmt.Printf("⚠️  Applied to the index only, it conflicts with unstaged changes: %v\n", err)
+					continue
+				}
+				fmt.Println("✓ Applied")
+			}
+			if len(applied) == 0 {
+				continue
+			}
+
+			// Re-run the agent against the patched files to confirm the fixes.
+			ctx, err = collectContext("")
+			if err != nil {
+				return fmt.Errorf("could not collect git context: %w", err)
+			}
+			rerun, err := runner.RunAgent(id, ctx)
+			if err != nil {
+				return fmt.Errorf("agent %s failed on re-run: %w", id, err)
+			}
+			for _, artifact := range applied {
+				if stillReported(rerun, artifact) {
+					fmt.Printf("⚠️  %s: %s in %s is still reported after the fix\n", id, artifact.Rule, artifact.Path)
+				} else {
+					fmt.Printf("✅ %s: %s in %s fixed\n", id, artifact.Rule, artifact.Path)
+				}
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d patch(es) could not be applied", failed)
+		}
+		return nil
+	},
+}
+
+// checkPatchFiles requires every file a patch artifact changes to be the file
+// the artifact names, so that an agent cannot slip other files into a patch
+// the user previews as a change to one file.
+func checkPatchFiles(artifact agent.AgentArtifact) error {
+	files, err := patch.Parse(artifact.Content)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		for _, path := range []string{f.OldPath, f.NewPath} {
+			if path != "" && path != artifact.Path {
+				return fmt.Errorf("patch for %s also changes %s", artifact.Path, path)
+			}
+		}
+	}
+	return nil
+}
+
+// stillReported reports whether result still contains a finding or patch for
+// the rule and file the applied patch addressed.
+func stillReported(result *agent.AgentResult, fix agent.AgentArtifact) bool {
+	for _, f := range result.Findings {
+		if f.Rule == fix.Rule && f.File == fix.Path {
+			return true
+		}
+	}
+	for _, a := range result.Artifacts {
+		if a.Type == "patch" && a.Rule == fix.Rule && a.Path == fix.Path {
+			return true
+		}
+	}
+	return false
+}
+
+func confirm(reader *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	fixCmd.Flags().BoolVarP(&fixYes, "yes", "y", false, "Apply every patch without asking")
+	fixCmd.Flags().BoolVar(&fixIndex, "index", false, "Apply patches to the index only, leaving the working tree as it is")
+	rootCmd.AddCommand(fixCmd)
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs git in the current directory and fails the test on error.
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// captureStdout returns what f prints.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestFixAppliesToIndexAndWorktree(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	t.Chdir(t.TempDir())
+	runGit(t, "init", "-q")
+	if err := os.MkdirAll(".verifier", 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "budgets:\n  daily_tokens: 1000000\nagents:\n  lint:\n    options:\n      linters: [gofmt]\n"
+	if err := os.WriteFile(filepath.Join(".verifier", "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("a.go", []byte("package a\n\nfunc A() int { return 1 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.go")
+	runGit(t, "commit", "-q", "-m", "init")
+
+	// Stage a badly formatted function, then add an unstaged one below it.
+	staged := "package a\n\nfunc A() int { return 1 }\n\nfunc B() int {return 2}\n"
+	if err := os.WriteFile("a.go", []byte(staged), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "add", "a.go")
+	if err := os.WriteFile("a.go", []byte(staged+"\n// unstaged\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixYes = true
+	defer func() { fixYes = false }()
+	var err error
+	out := captureStdout(t, func() { err = fixCmd.RunE(fixCmd, []string{"lint"}) })
+	if err != nil {
+		t.Fatalf("fix failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "✅ lint: lint/gofmt in a.go fixed") {
+		t.Errorf("fix output does not report the fix as confirmed:\n%s", out)
+	}
+
+	fixed := "package a\n\nfunc A() int { return 1 }\n\nfunc B() int { return 2 }\n"
+	if got := runGit(t, "show", ":a.go"); got != fixed {
+		t.Errorf("index a.go = %q, want %q", got, fixed)
+	}
+	if got, _ := os.ReadFile("a.go"); string(got) != fixed+"\n// unstaged\n" {
+		t.Errorf("working tree a.go = %q, want the fix with the unstaged line kept", got)
+	}
+}
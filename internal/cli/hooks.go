@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/autodevopsai/verifier-go/internal/agent"
+	"github.com/autodevopsai/verifier-go/internal/config"
+	"github.com/spf13/cobra"
+)
+
+const hookMarker = "# Installed by verifier"
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage and run git hooks",
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install git hooks for every hook configured in config.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w. Please run 'verifier init'", err)
+		}
+
+		out, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+		if err != nil {
+			return fmt.Errorf("failed to locate git hooks directory: %w", err)
+		}
+		hooksDir := strings.TrimSpace(string(out))
+		if err := os.MkdirAll(hooksDir, 0755); err != nil {
+			return err
+		}
+
+		for hook := range cfg.Hooks {
+			path := filepath.Join(hooksDir, hook)
+			if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) && !force {
+				fmt.Printf("Skipping %s: a hook not managed by verifier exists. Use --force to overwrite.\n", hook)
+				continue
+			}
+			script := fmt.Sprintf("#!/bin/sh\n%s\nexec verifier hooks run %s \"$@\"\n", hookMarker, hook)
+			if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+				return fmt.Errorf("failed to install %s hook: %w", hook, err)
+			}
+			fmt.Printf("✓ Installed %s hook\n", hook)
+		}
+		return nil
+	},
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:   "run [hook] [hook-args...]",
+	Short: "Run the agents configured for a git hook",
+	Long: `Run the agents listed under hooks.<hook> in config.yaml. For the commit-msg
+hook, the first argument is the commit message file git passes to the hook.
+Exits with an error when any agent reports blocking severity or fails, e.g.
+times out, so that a broken agent does not let the commit through.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hook := args[0]
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w. Please run 'verifier init'", err)
+		}
+		if err := agent.LoadAgents(cfg); err != nil {
+			return err
+		}
+		agentIDs := cfg.Hooks[hook]
+		if len(agentIDs) == 0 {
+			return nil
+		}
+
+		msgFile := ""
+		if hook == "commit-msg" && len(args) > 1 {
+			msgFile = args[1]
+		}
+		ctx, err := collectContext(msgFile)
+		if err != nil {
+			return fmt.Errorf("could not collect git context: %w", err)
+		}
+
+		runner := agent.NewAgentRunner(cfg)
+		var blocking []string
+		for _, id := range agentIDs {
+			result, err := runner.RunAgent(id, ctx)
+			if err != nil {
+				return fmt.Errorf("agent %s failed: %w", id, err)
+			}
+			printHookResult(result)
+			switch {
+			case result.Status == "failure":
+				blocking = append(blocking, id+" (failed)")
+			case result.Severity == "blocking":
+				blocking = append(blocking, id)
+			}
+		}
+
+		if len(blocking) > 0 {
+			return fmt.Errorf("%s hook blocked by: %s", hook, strings.Join(blocking, ", "))
+		}
+		return nil
+	},
+}
+
+// printHookResult writes a short, human-readable summary of a result.
+func printHookResult(result *agent.AgentResult) {
+	icon := "✅"
+	switch {
+	case result.Status == "skipped":
+		icon = "⏭️"
+	case result.Status == "failure" || result.Severity == "blocking":
+		icon = "❌"
+	case result.Severity == "warning":
+		icon = "⚠️"
+	}
+	line := fmt.Sprintf("%s %s", icon, result.AgentID)
+	if result.Error != "" {
+		line += ": " + result.Error
+	}
+	if result.HiddenFindings > 0 {
+		line += fmt.Sprintf(" (%d pre-existing issue(s) outside the change hidden)", result.HiddenFindings)
+	}
+	fmt.Println(line)
+
+	for _, f := range result.Findings {
+		loc := f.File
+		if f.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", f.File, f.Line)
+			if f.Column > 0 {
+				loc += fmt.Sprintf(":%d", f.Column)
+			}
+		}
+		if loc != "" {
+			loc += " "
+		}
+		fmt.Printf("   [%s] %s%s\n", f.Severity, loc, f.Message)
+	}
+}
+
+func init() {
+	hooksInstallCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing hooks")
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksRunCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/autodevopsai/verifier-go/internal/agent"
 	"github.com/autodevopsai/verifier-go/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -37,12 +38,18 @@ var initCmd = &cobra.Command{
 			Thresholds: config.Thresholds{
 				DriftScore:    30,
 				SecurityRisk:  5,
-				CoverageDelta: -5,
+				CoverageDelta: config.DefaultCoverageDelta,
 			},
 			Hooks: map[string][]string{
 				"pre-commit": {"lint", "security-scan"},
+				"commit-msg": {"commit-msg"},
 			},
 		}
+		agents, err := agent.DefaultAgentSettings(defaultConfig)
+		if err != nil {
+			return fmt.Errorf("failed to collect agent defaults: %w", err)
+		}
+		defaultConfig.Agents = agents
 
 		if err := config.Save(defaultConfig); err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
@@ -53,7 +60,7 @@ var initCmd = &cobra.Command{
 		if err := os.WriteFile(envPath, []byte(envContent), 0600); err != nil {
 			return fmt.Errorf("failed to create .env file: %w", err)
 		}
-		
+
 		fmt.Println("✓ Verifier initialized successfully!")
 		return nil
 	},
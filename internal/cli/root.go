@@ -1,12 +1,9 @@
 package cli
 
 import (
-	"fmt"
-	"os"
-	
-	"github.com/spf13/cobra"
 	"github.com/autodevopsai/verifier-go/internal/util"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 )
 
 var verbose bool
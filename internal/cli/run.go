@@ -11,17 +11,26 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var baseRef string
+var headRef string
+var messageFile string
+var suggest bool
+var fix bool
+
 var runCmd = &cobra.Command{
 	Use:   "run [agent-id]",
 	Short: "Run a specified verifier agent",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		agentID := args[0]
-		
+
 		cfg, err := config.Load()
 		if err != nil {
 			return fmt.Errorf("failed to load configuration: %w. Please run 'verifier init'", err)
 		}
+		if err := agent.LoadAgents(cfg); err != nil {
+			return err
+		}
 
 		if _, err := agent.GetAgent(agentID, cfg); err != nil {
 			available := strings.Join(agent.ListAgents(), ", ")
@@ -30,10 +39,16 @@ var runCmd = &cobra.Command{
 
 		fmt.Printf("Running agent: %s...\n", agentID)
 
-		ctx, err := context.CollectGitContext()
+		ctx, err := collectContext(messageFile)
 		if err != nil {
+			// Without the range there is nothing meaningful to check.
+			if baseRef != "" {
+				return fmt.Errorf("could not collect git context: %w", err)
+			}
 			fmt.Printf("Warning: could not collect git context: %v\n", err)
 		}
+		ctx.Suggest = suggest
+		ctx.Fix = fix
 
 		runner := agent.NewAgentRunner(cfg)
 		result, err := runner.RunAgent(agentID, ctx)
@@ -47,6 +62,35 @@ var runCmd = &cobra.Command{
 	},
 }
 
+// collectContext gathers the agent context for the staged changes, or for the
+// --base/--head range when set. messageFile, when non-empty, is the commit
+// message file passed to the commit-msg hook.
+func collectContext(messageFile string) (agent.AgentContext, error) {
+	var ctx agent.AgentContext
+	var err error
+	if baseRef != "" {
+		ctx, err = context.CollectRangeContext(baseRef, headRef)
+	} else {
+		ctx, err = context.CollectGitContext()
+	}
+	if ctx.Env == nil {
+		ctx.Env = make(map[string]string)
+	}
+	if err != nil {
+		return ctx, err
+	}
+
+	if messageFile != "" {
+		ctx.CommitMessage, err = context.ReadCommitMessage(messageFile)
+	}
+	return ctx, err
+}
+
 func init() {
+	runCmd.Flags().StringVar(&baseRef, "base", "", "Check the changes between this revision and --head instead of the staged changes")
+	runCmd.Flags().StringVar(&headRef, "head", "HEAD", "Revision holding the changes when --base is set")
+	runCmd.Flags().StringVar(&messageFile, "message-file", "", "Commit message file to check, as passed to the commit-msg hook")
+	runCmd.Flags().BoolVar(&suggest, "suggest", false, "Ask agents that support it to suggest content, e.g. a commit message")
+	runCmd.Flags().BoolVar(&fix, "fix", false, "Ask agents that support it to fix the staged files and restage them, e.g. lint")
 	rootCmd.AddCommand(runCmd)
 }
@@ -55,7 +55,7 @@ var tokenUsageCmd = &cobra.Command{
 
 		// Table output
 		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Agent", "Calls", "Tokens", "Cost"})
+		table.Header("Agent", "Calls", "Tokens", "Cost")
 		for agentID, data := range usage {
 			row := []string{
 				agentID,
@@ -69,7 +69,7 @@ var tokenUsageCmd = &cobra.Command{
 
 		fmt.Printf("\nTotal Tokens: %d\n", totalTokens)
 		fmt.Printf("Total Cost: $%.4f\n", totalCost)
-		
+
 		return nil
 	},
 }
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -12,37 +14,127 @@ import (
 
 // Config corresponds to the structure of .verifier/config.yaml
 type Config struct {
-	Models    Models             `mapstructure:"models"`
-	Providers Providers          `mapstructure:"providers"`
-	Budgets   Budgets            `mapstructure:"budgets"`
-	Thresholds Thresholds         `mapstructure:"thresholds"`
-	Hooks     map[string][]string `mapstructure:"hooks"`
+	Models     Models              `mapstructure:"models" yaml:"models"`
+	Providers  Providers           `mapstructure:"providers" yaml:"providers"`
+	Budgets    Budgets             `mapstructure:"budgets" yaml:"budgets"`
+	Thresholds Thresholds          `mapstructure:"thresholds" yaml:"thresholds"`
+	Hooks      map[string][]string `mapstructure:"hooks" yaml:"hooks"`
+	// Agents holds per-agent settings keyed by agent ID.
+	Agents  map[string]AgentSettings `mapstructure:"agents" yaml:"agents,omitempty"`
+	Plugins map[string]Plugin        `mapstructure:"plugins" yaml:"plugins,omitempty"`
+	// WasmAgents are WASI modules run in a sandbox inside the verifier
+	// process. Modules in .verifier/agents/*.wasm are picked up too.
+	WasmAgents map[string]WasmAgent `mapstructure:"wasm_agents" yaml:"wasm_agents,omitempty"`
+	// PromptAgents are LLM agents defined in config rather than Go code.
+	// More can be defined one per file in .verifier/agents/*.yaml.
+	PromptAgents []PromptAgent `mapstructure:"prompt_agents" yaml:"prompt_agents,omitempty"`
 }
 
 type Models struct {
-	Primary  string `mapstructure:"primary"`
-	Fallback string `mapstructure:"fallback"`
+	Primary  string `mapstructure:"primary" yaml:"primary"`
+	Fallback string `mapstructure:"fallback" yaml:"fallback"`
 }
 
 type Providers struct {
-	OpenAI    ProviderAPIKey `mapstructure:"openai"`
-	Anthropic ProviderAPIKey `mapstructure:"anthropic"`
+	OpenAI    ProviderAPIKey `mapstructure:"openai" yaml:"openai"`
+	Anthropic ProviderAPIKey `mapstructure:"anthropic" yaml:"anthropic"`
 }
 
 type ProviderAPIKey struct {
-	APIKey string `mapstructure:"api_key"`
+	APIKey string `mapstructure:"api_key" yaml:"api_key"`
 }
 
 type Budgets struct {
-	DailyTokens     int `mapstructure:"daily_tokens"`
-	PerCommitTokens int `mapstructure:"per_commit_tokens"`
-	MonthlyCost     int `mapstructure:"monthly_cost"`
+	DailyTokens     int `mapstructure:"daily_tokens" yaml:"daily_tokens"`
+	PerCommitTokens int `mapstructure:"per_commit_tokens" yaml:"per_commit_tokens"`
+	MonthlyCost     int `mapstructure:"monthly_cost" yaml:"monthly_cost"`
 }
 
+// DefaultCoverageDelta is the coverage drop, in percentage points, past which
+// the coverage agent blocks when thresholds.coverage_delta is not set.
+const DefaultCoverageDelta = -5
+
 type Thresholds struct {
-	DriftScore    int `mapstructure:"drift_score"`
-	SecurityRisk  int `mapstructure:"security_risk"`
-	CoverageDelta int `mapstructure:"coverage_delta"`
+	DriftScore    int `mapstructure:"drift_score" yaml:"drift_score"`
+	SecurityRisk  int `mapstructure:"security_risk" yaml:"security_risk"`
+	CoverageDelta int `mapstructure:"coverage_delta" yaml:"coverage_delta"`
+}
+
+// AgentSettings configures one agent under agents.<id>.
+type AgentSettings struct {
+	// Enabled defaults to true. Disabled agents are skipped wherever they
+	// are run.
+	Enabled *bool `mapstructure:"enabled" yaml:"enabled,omitempty"`
+	// Model replaces models.primary for an LLM agent.
+	Model string `mapstructure:"model" yaml:"model,omitempty"`
+	// Timeout bounds one run of the agent, e.g. "2m". Unset means no limit.
+	Timeout string `mapstructure:"timeout" yaml:"timeout,omitempty"`
+	// Include and Exclude are globs selecting the changed files the agent
+	// sees. A glob without a slash matches the file name in any directory.
+	Include []string `mapstructure:"include" yaml:"include,omitempty"`
+	Exclude []string `mapstructure:"exclude" yaml:"exclude,omitempty"`
+	// NewCode limits the reported findings to the change: "all" (default),
+	// "changed-files" or "changed-lines". Hidden findings are still counted.
+	NewCode string `mapstructure:"new_code" yaml:"new_code,omitempty"`
+	// Thresholds override the agent's numeric limits by name, e.g.
+	// security-scan's risk_score.
+	Thresholds map[string]float64 `mapstructure:"thresholds" yaml:"thresholds,omitempty"`
+	// Options are the agent's own settings, decoded into its typed options
+	// with their defaults and validation.
+	Options map[string]any `mapstructure:"options" yaml:"options,omitempty"`
+}
+
+// AgentEnabled reports whether the agent with the given ID is enabled.
+func (c *Config) AgentEnabled(id string) bool {
+	s, ok := c.Agents[id]
+	return !ok || s.Enabled == nil || *s.Enabled
+}
+
+// Plugin declares an external agent executable that speaks the plugin
+// protocol. Executables in .verifier/agents/ are discovered without config.
+type Plugin struct {
+	Command string   `mapstructure:"command" yaml:"command"`
+	Args    []string `mapstructure:"args" yaml:"args,omitempty"`
+	// Timeout bounds a whole run, e.g. "2m". Defaults to one minute.
+	Timeout string `mapstructure:"timeout" yaml:"timeout,omitempty"`
+}
+
+// WasmAgent declares a sandboxed agent compiled to a WASI module.
+type WasmAgent struct {
+	Module      string `mapstructure:"module" yaml:"module"`
+	Description string `mapstructure:"description" yaml:"description,omitempty"`
+	// Timeout bounds a whole run, e.g. "30s". Defaults to one minute.
+	Timeout string `mapstructure:"timeout" yaml:"timeout,omitempty"`
+	// MemoryMB caps the module's linear memory. Defaults to 256.
+	MemoryMB int `mapstructure:"memory_mb" yaml:"memory_mb,omitempty"`
+}
+
+// PromptAgent declares an agent that asks the model about the change and
+// parses its JSON answer.
+type PromptAgent struct {
+	ID          string `mapstructure:"id" yaml:"id"`
+	Description string `mapstructure:"description" yaml:"description"`
+	// Model defaults to models.primary.
+	Model  string `mapstructure:"model" yaml:"model,omitempty"`
+	System string `mapstructure:"system" yaml:"system,omitempty"`
+	// Prompt is a Go text/template executed over the agent context.
+	Prompt string `mapstructure:"prompt" yaml:"prompt"`
+	// Files are globs the changed files must match for the agent to run.
+	// Empty means the agent always runs.
+	Files []string `mapstructure:"files" yaml:"files,omitempty"`
+	// Schema is a JSON schema the model output must satisfy.
+	Schema map[string]any `mapstructure:"schema" yaml:"schema,omitempty"`
+	// Severity rules are tried in order; the first whose condition holds
+	// sets the result severity.
+	Severity []SeverityRule `mapstructure:"severity" yaml:"severity,omitempty"`
+}
+
+// SeverityRule maps model output to a severity. When is a text/template
+// executed over the output that must render "true", e.g.
+// "{{ gt .risk_score 7.0 }}".
+type SeverityRule struct {
+	When     string `mapstructure:"when" yaml:"when"`
+	Severity string `mapstructure:"severity" yaml:"severity"`
 }
 
 // Load reads configuration using Viper, respecting files, env vars, and .env
@@ -58,6 +150,9 @@ func Load() (*Config, error) {
 	v.SetEnvPrefix("VERIFIER")
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
+	// A default rather than a zero check, so that coverage_delta: 0 blocks on
+	// any drop.
+	v.SetDefault("thresholds.coverage_delta", DefaultCoverageDelta)
 
 	var cfg Config
 
@@ -70,6 +165,16 @@ func Load() (*Config, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
+	// Viper lowercases every map key, which breaks the JSON schemas of prompt
+	// agents (additionalProperties, camelCase properties). Read them from the
+	// file as written.
+	if path := v.ConfigFileUsed(); path != "" {
+		agents, err := readPromptAgents(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.PromptAgents = agents
+	}
 
 	// Manually bind from common env vars if not set by viper
 	if cfg.Providers.OpenAI.APIKey == "" {
@@ -82,6 +187,22 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// readPromptAgents decodes the prompt_agents section of the config file at
+// path, keeping the case of map keys.
+func readPromptAgents(path string) ([]PromptAgent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		PromptAgents []PromptAgent `yaml:"prompt_agents"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc.PromptAgents, nil
+}
+
 // Save writes the configuration to .verifier/config.yaml
 func Save(cfg *Config) error {
 	data, err := yaml.Marshal(cfg)
@@ -94,3 +215,54 @@ func Save(cfg *Config) error {
 	}
 	return os.WriteFile(configPath, data, 0644)
 }
+
+// SetAgentEnabled sets agents.<id>.enabled in .verifier/config.yaml. It edits
+// the file rather than saving a loaded Config, so comments are kept and values
+// that came from the environment, such as API keys, are not written out.
+func SetAgentEnabled(id string, enabled bool) error {
+	configPath := filepath.Join(".verifier", "config.yaml")
+	var doc yaml.Node
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", configPath)
+	}
+
+	setting := mappingEntry(mappingEntry(mappingEntry(root, "agents"), id), "enabled")
+	*setting = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(enabled)}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, out, 0644)
+}
+
+// mappingEntry returns the value node for key in mapping, adding an empty
+// mapping under key when it is missing or null.
+func mappingEntry(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Tag == "!!null" {
+				*value = yaml.Node{Kind: yaml.MappingNode}
+			}
+			return value
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
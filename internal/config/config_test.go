@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadKeepsPromptAgentSchemaKeys(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".verifier"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `prompt_agents:
+  - id: naming
+    prompt: "Check names"
+    schema:
+      type: object
+      additionalProperties: false
+      required: [riskScore]
+      properties:
+        riskScore: {type: number}
+`
+	if err := os.WriteFile(filepath.Join(dir, ".verifier", "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.PromptAgents) != 1 {
+		t.Fatalf("got %d prompt agents, want 1", len(cfg.PromptAgents))
+	}
+	want := map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []any{"riskScore"},
+		"properties":           map[string]any{"riskScore": map[string]any{"type": "number"}},
+	}
+	if got := cfg.PromptAgents[0].Schema; !reflect.DeepEqual(got, want) {
+		t.Errorf("schema = %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadCoverageDelta(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   int
+	}{
+		{"unset", "budgets:\n  daily_tokens: 10\n", DefaultCoverageDelta},
+		{"zero blocks on any drop", "thresholds:\n  coverage_delta: 0\n", 0},
+		{"set", "thresholds:\n  coverage_delta: -2\n", -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, ".verifier"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, ".verifier", "config.yaml"), []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Chdir(dir)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Thresholds.CoverageDelta != tt.want {
+				t.Errorf("coverage_delta = %d, want %d", cfg.Thresholds.CoverageDelta, tt.want)
+			}
+		})
+	}
+}
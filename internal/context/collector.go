@@ -3,11 +3,13 @@ package context
 import (
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/autodevopsai/verifier-go/internal/agent"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -20,11 +22,18 @@ func CollectGitContext() (agent.AgentContext, error) {
 		return ctx, fmt.Errorf("failed to open git repository: %w", err)
 	}
 
+	if wt, err := repo.Worktree(); err == nil {
+		ctx.RepoPath = wt.Filesystem.Root()
+	}
+
 	// Get current branch
 	head, err := repo.Head()
-	if err == nil {
-		ctx.Branch = head.Name().Short()
+	if err != nil {
+		return ctx, fmt.Errorf("failed to resolve HEAD: %w", err)
 	}
+	ctx.Branch = head.Name().Short()
+	// Staged changes are compared against the current HEAD commit.
+	ctx.BaseRef = head.Hash().String()
 
 	// Get staged files and diff
 	idx, err := repo.Storer.Index()
@@ -43,7 +52,7 @@ func CollectGitContext() (agent.AgentContext, error) {
 	}
 
 	var stagedFiles []string
-	var diffBuilder strings.Builder
+	var filePatches []fdiff.FilePatch
 
 	for _, entry := range idx.Entries {
 		// Check if the file is modified in the index compared to HEAD
@@ -51,37 +60,182 @@ func CollectGitContext() (agent.AgentContext, error) {
 		if err != nil {
 			continue // Skip if blob cannot be retrieved
 		}
+		to := &blobFile{hash: entry.Hash, mode: entry.Mode, path: entry.Name}
 
 		headEntry, err := headTree.FindEntry(entry.Name)
 		if err != nil {
 			// File is new in index
-		
-stagedFiles = append(stagedFiles, entry.Name)
-		
-			reader, _ := obj.Reader()
-			content, _ := io.ReadAll(reader)
-			diffBuilder.WriteString(fmt.Sprintf("---\n+++ b/%s\n@@ -0,0 +1,%d @@\n+%s\n", entry.Name, strings.Count(string(content), "\n")+1, string(content)))
+			stagedFiles = append(stagedFiles, entry.Name)
+
+			content, err := blobContent(obj)
+			if err != nil {
+				continue
+			}
+			filePatches = append(filePatches, newFilePatch(nil, to, "", content))
 			continue
 		}
 
 		if entry.Hash != headEntry.Hash {
 			// File is modified
-		
-stagedFiles = append(stagedFiles, entry.Name)
+			stagedFiles = append(stagedFiles, entry.Name)
 
 			headObj, err := repo.BlobObject(headEntry.Hash)
 			if err != nil {
 				continue
 			}
 
-			patch, err := headObj.Patch(obj)
-			if err == nil {
-				diffBuilder.WriteString(patch.String())
+			oldContent, err := blobContent(headObj)
+			if err != nil {
+				continue
 			}
+			newContent, err := blobContent(obj)
+			if err != nil {
+				continue
+			}
+			from := &blobFile{hash: headEntry.Hash, mode: headEntry.Mode, path: entry.Name}
+			filePatches = append(filePatches, newFilePatch(from, to, oldContent, newContent))
 		}
 	}
 	ctx.Files = stagedFiles
-	ctx.Diff = diffBuilder.String()
+
+	diff, err := encodePatch(filePatches)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to encode staged diff: %w", err)
+	}
+	ctx.Diff = diff
+
+	return ctx, nil
+}
+
+// encodePatch renders file patches as a unified diff with three lines of context.
+func encodePatch(filePatches []fdiff.FilePatch) (string, error) {
+	var sb strings.Builder
+	if err := fdiff.NewUnifiedEncoder(&sb, fdiff.DefaultContextLines).Encode(&blobPatch{filePatches: filePatches}); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func blobContent(blob *object.Blob) (string, error) {
+	reader, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// CollectRangeContext gathers the changes between two revisions, as a pull
+// request would see them: the diff runs from the merge base of base and head
+// to head.
+func CollectRangeContext(base, head string) (agent.AgentContext, error) {
+	var ctx agent.AgentContext
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return ctx, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	if wt, err := repo.Worktree(); err == nil {
+		ctx.RepoPath = wt.Filesystem.Root()
+	}
+
+	baseCommit, err := resolveCommit(repo, base)
+	if err != nil {
+		return ctx, err
+	}
+	headCommit, err := resolveCommit(repo, head)
+	if err != nil {
+		return ctx, err
+	}
+
+	bases, err := baseCommit.MergeBase(headCommit)
+	if err != nil || len(bases) == 0 {
+		return ctx, fmt.Errorf("no merge base between %s and %s", base, head)
+	}
+	mergeBase := bases[0]
+
+	ctx.Branch = head
+	ctx.BaseRef = mergeBase.Hash.String()
+	ctx.HeadRef = headCommit.Hash.String()
+
+	patch, err := mergeBase.Patch(headCommit)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to diff %s..%s: %w", base, head, err)
+	}
+	for _, fp := range patch.FilePatches() {
+		if _, to := fp.Files(); to != nil {
+			ctx.Files = append(ctx.Files, to.Path())
+		}
+	}
+	ctx.Diff = patch.String()
+
+	commits, err := commitsBetween(baseCommit, headCommit)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to list commits %s..%s: %w", base, head, err)
+	}
+	for _, c := range commits {
+		ctx.Commits = append(ctx.Commits, agent.Commit{Hash: c.Hash.String(), Message: c.Message})
+	}
 
 	return ctx, nil
 }
+
+// commitsBetween returns the commits reachable from head but not from base,
+// newest first, like git rev-list base..head. Stopping at the merge base alone
+// is not enough: once head has merged base, the walk reaches the commits
+// before the fork point through the merge's other parent.
+func commitsBetween(base, head *object.Commit) ([]*object.Commit, error) {
+	excluded := make(map[plumbing.Hash]bool)
+	err := object.NewCommitPreorderIter(base, nil, nil).ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*object.Commit
+	err = object.NewCommitPreorderIter(head, excluded, nil).ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	return commits, err
+}
+
+// ReadCommitMessage reads the message file git passes to the commit-msg hook,
+// dropping comment lines and anything below the scissors line that
+// "git commit -v" adds.
+func ReadCommitMessage(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit message: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "# ------------------------ >8 ------------------------") {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+func resolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", rev, err)
+	}
+	return commit, nil
+}
@@ -0,0 +1,89 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFile writes file and commits it with the given parents, or on top of
+// HEAD when parents is nil.
+func commitFile(t *testing.T, repo *git.Repository, dir, file, msg string, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(msg+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := wt.Commit(msg, &git.CommitOptions{
+		Author:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Parents: parents,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func checkout(t *testing.T, repo *git.Repository, branch string, create bool) {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectRangeContextAfterMergingBase(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.Main},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// main:    A---C
+	//           \   \
+	// feature:   B---M---D
+	commitFile(t, repo, dir, "a.txt", "A")
+	checkout(t, repo, "feature", true)
+	b := commitFile(t, repo, dir, "b.txt", "B")
+	checkout(t, repo, "main", false)
+	c := commitFile(t, repo, dir, "c.txt", "C")
+	checkout(t, repo, "feature", false)
+	commitFile(t, repo, dir, "c.txt", "M", b, c)
+	commitFile(t, repo, dir, "d.txt", "D")
+
+	t.Chdir(dir)
+	ctx, err := CollectRangeContext("main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, commit := range ctx.Commits {
+		got = append(got, strings.TrimSpace(commit.Message))
+	}
+	slices.Sort(got)
+	if want := []string{"B", "D", "M"}; !slices.Equal(got, want) {
+		t.Errorf("commits = %v, want %v", got, want)
+	}
+	if ctx.BaseRef != c.String() {
+		t.Errorf("BaseRef = %s, want merge base %s", ctx.BaseRef, c)
+	}
+}
@@ -0,0 +1,72 @@
+package context
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// blobPatch adapts a pair of blob contents to go-git's diff.Patch interface so
+// the unified encoder can render it.
+type blobPatch struct {
+	filePatches []fdiff.FilePatch
+}
+
+func (p *blobPatch) FilePatches() []fdiff.FilePatch { return p.filePatches }
+func (p *blobPatch) Message() string                { return "" }
+
+type blobFilePatch struct {
+	from, to fdiff.File
+	chunks   []fdiff.Chunk
+}
+
+func (p *blobFilePatch) IsBinary() bool                  { return false }
+func (p *blobFilePatch) Files() (fdiff.File, fdiff.File) { return p.from, p.to }
+func (p *blobFilePatch) Chunks() []fdiff.Chunk           { return p.chunks }
+
+type blobFile struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+	path string
+}
+
+func (f *blobFile) Hash() plumbing.Hash     { return f.hash }
+func (f *blobFile) Mode() filemode.FileMode { return f.mode }
+func (f *blobFile) Path() string            { return f.path }
+
+type blobChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c *blobChunk) Content() string       { return c.content }
+func (c *blobChunk) Type() fdiff.Operation { return c.op }
+
+// newFilePatch builds a file patch between two versions of a file. A nil from
+// or to marks an added or deleted file respectively.
+func newFilePatch(from, to *blobFile, fromContent, toContent string) fdiff.FilePatch {
+	var chunks []fdiff.Chunk
+	for _, d := range diff.Do(fromContent, toContent) {
+		var op fdiff.Operation
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			op = fdiff.Equal
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		}
+		chunks = append(chunks, &blobChunk{content: d.Text, op: op})
+	}
+
+	fp := &blobFilePatch{chunks: chunks}
+	if from != nil {
+		fp.from = from
+	}
+	if to != nil {
+		fp.to = to
+	}
+	return fp
+}
@@ -0,0 +1,318 @@
+package osv
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Vulnerability is the subset of an OSV record needed to match a single
+// package, as stored in the local database.
+type Vulnerability struct {
+	ID       string     `json:"id"`
+	Aliases  []string   `json:"aliases,omitempty"`
+	Summary  string     `json:"summary,omitempty"`
+	Severity string     `json:"severity,omitempty"` // as published, e.g. "HIGH"
+	Affected []Affected `json:"affected"`
+}
+
+type Affected struct {
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// record is the on-the-wire OSV schema, limited to the fields we import.
+type record struct {
+	ID               string   `json:"id"`
+	Aliases          []string `json:"aliases"`
+	Summary          string   `json:"summary"`
+	Details          string   `json:"details"`
+	Withdrawn        string   `json:"withdrawn"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges   []Range  `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+}
+
+// Database is a local mirror of OSV data, stored as one JSON file per
+// ecosystem mapping package names to their vulnerabilities.
+type Database struct {
+	dir    string
+	loaded map[string]map[string][]Vulnerability
+}
+
+func NewDatabase() *Database {
+	return &Database{
+		dir:    filepath.Join(".verifier", "osv"),
+		loaded: make(map[string]map[string][]Vulnerability),
+	}
+}
+
+// Exists reports whether any ecosystem has been imported.
+func (d *Database) Exists() bool {
+	files, _ := filepath.Glob(filepath.Join(d.dir, "*.json"))
+	return len(files) > 0
+}
+
+// Import merges an OSV zip export (such as the per-ecosystem all.zip) into the
+// database and returns the number of vulnerabilities read.
+func (d *Database) Import(zipPath string) (int, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	updates := make(map[string]map[string]map[string]Vulnerability) // ecosystem -> package -> id
+	count := 0
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return count, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return count, err
+		}
+
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return count, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if r.Withdrawn != "" {
+			continue
+		}
+		count++
+
+		summary := r.Summary
+		if summary == "" {
+			summary = firstLine(r.Details)
+		}
+		for _, aff := range r.Affected {
+			eco := ecosystemBase(aff.Package.Ecosystem)
+			name := NormalizeName(eco, aff.Package.Name)
+			if eco == "" || name == "" {
+				continue
+			}
+			if updates[eco] == nil {
+				updates[eco] = make(map[string]map[string]Vulnerability)
+			}
+			if updates[eco][name] == nil {
+				updates[eco][name] = make(map[string]Vulnerability)
+			}
+			v, ok := updates[eco][name][r.ID]
+			if !ok {
+				v = Vulnerability{ID: r.ID, Aliases: r.Aliases, Summary: summary, Severity: r.DatabaseSpecific.Severity}
+			}
+			v.Affected = append(v.Affected, Affected{Ranges: aff.Ranges, Versions: aff.Versions})
+			updates[eco][name][r.ID] = v
+		}
+	}
+
+	for eco, pkgs := range updates {
+		db, err := d.load(eco)
+		if err != nil {
+			return count, err
+		}
+		for name, vulns := range pkgs {
+			merged := make(map[string]Vulnerability)
+			for _, v := range db[name] {
+				merged[v.ID] = v
+			}
+			for id, v := range vulns {
+				merged[id] = v
+			}
+			list := make([]Vulnerability, 0, len(merged))
+			for _, v := range merged {
+				list = append(list, v)
+			}
+			sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+			db[name] = list
+		}
+		if err := d.save(eco, db); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// Lookup returns the vulnerabilities that affect version of the named package.
+func (d *Database) Lookup(ecosystem, name, version string) ([]Vulnerability, error) {
+	db, err := d.load(ecosystem)
+	if err != nil {
+		return nil, err
+	}
+	var matches []Vulnerability
+	for _, v := range db[NormalizeName(ecosystem, name)] {
+		if v.Affects(version) {
+			matches = append(matches, v)
+		}
+	}
+	return matches, nil
+}
+
+// Affects reports whether version falls within any affected range or listed
+// version of the vulnerability.
+func (v Vulnerability) Affects(version string) bool {
+	version = strings.TrimPrefix(version, "v")
+	for _, aff := range v.Affected {
+		for _, listed := range aff.Versions {
+			if strings.TrimPrefix(listed, "v") == version {
+				return true
+			}
+		}
+		for _, r := range aff.Ranges {
+			if r.Type == "GIT" {
+				continue
+			}
+			if r.affects(version) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FixedVersions lists the versions that fix the vulnerability.
+func (v Vulnerability) FixedVersions() []string {
+	var fixed []string
+	for _, aff := range v.Affected {
+		for _, r := range aff.Ranges {
+			for _, e := range r.Events {
+				if e.Fixed != "" && r.Type != "GIT" {
+					fixed = append(fixed, e.Fixed)
+				}
+			}
+		}
+	}
+	return fixed
+}
+
+// affects evaluates the range events in order, as described by the OSV schema.
+func (r Range) affects(version string) bool {
+	events := append([]Event(nil), r.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return CompareVersions(eventVersion(events[i]), eventVersion(events[j])) < 0
+	})
+
+	affected := false
+	for _, e := range events {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || CompareVersions(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if CompareVersions(version, e.Fixed) >= 0 {
+				affected = false
+			}
+		case e.LastAffected != "":
+			if CompareVersions(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		case e.Limit != "":
+			if CompareVersions(version, e.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+func eventVersion(e Event) string {
+	switch {
+	case e.Introduced != "":
+		if e.Introduced == "0" {
+			return ""
+		}
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	case e.LastAffected != "":
+		return e.LastAffected
+	default:
+		return e.Limit
+	}
+}
+
+func (d *Database) load(ecosystem string) (map[string][]Vulnerability, error) {
+	if db, ok := d.loaded[ecosystem]; ok {
+		return db, nil
+	}
+	db := make(map[string][]Vulnerability)
+	data, err := os.ReadFile(d.path(ecosystem))
+	if err == nil {
+		if err := json.Unmarshal(data, &db); err != nil {
+			return nil, fmt.Errorf("corrupt OSV database for %s: %w", ecosystem, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	d.loaded[ecosystem] = db
+	return db, nil
+}
+
+func (d *Database) save(ecosystem string, db map[string][]Vulnerability) error {
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(db)
+	if err != nil {
+		return err
+	}
+	d.loaded[ecosystem] = db
+	return os.WriteFile(d.path(ecosystem), data, 0644)
+}
+
+func (d *Database) path(ecosystem string) string {
+	return filepath.Join(d.dir, ecosystem+".json")
+}
+
+// NormalizeName canonicalises package names the way the ecosystem compares
+// them; PyPI names are case-insensitive and treat "_", "." and "-" alike.
+func NormalizeName(ecosystem, name string) string {
+	if ecosystem == "PyPI" {
+		name = strings.ToLower(name)
+		name = strings.NewReplacer("_", "-", ".", "-").Replace(name)
+	}
+	return name
+}
+
+// ecosystemBase drops an ecosystem suffix such as "Debian:11".
+func ecosystemBase(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
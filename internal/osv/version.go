@@ -0,0 +1,102 @@
+package osv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions orders two version strings, returning -1, 0 or 1. It
+// understands semver (with or without a leading "v") and the common PEP 440
+// forms: numeric segments compare numerically, and a pre-release such as
+// "1.0.0-rc1" or "1.0rc1" sorts before its release.
+func CompareVersions(a, b string) int {
+	pa, pb := splitVersion(a), splitVersion(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y string
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if c := compareSegment(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// splitVersion breaks a version into alternating numeric and alphabetic
+// segments, dropping separators and build metadata.
+func splitVersion(v string) []string {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	var segs []string
+	var cur strings.Builder
+	digit := false
+	flush := func() {
+		if cur.Len() > 0 {
+			segs = append(segs, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range v {
+		isDigit := r >= '0' && r <= '9'
+		isAlpha := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isDigit && !isAlpha {
+			flush()
+			continue
+		}
+		if cur.Len() > 0 && isDigit != digit {
+			flush()
+		}
+		digit = isDigit
+		cur.WriteRune(r)
+	}
+	flush()
+	return segs
+}
+
+// compareSegment compares two segments. A missing segment ranks above an
+// alphabetic one (so "1.0" > "1.0rc1") but below a numeric one.
+func compareSegment(x, y string) int {
+	if x == y {
+		return 0
+	}
+	xn, xErr := strconv.Atoi(x)
+	yn, yErr := strconv.Atoi(y)
+	switch {
+	case x == "":
+		if yErr == nil {
+			if yn == 0 {
+				return 0 // "1.0" == "1"
+			}
+			return -1
+		}
+		return 1
+	case y == "":
+		if xErr == nil {
+			if xn == 0 {
+				return 0
+			}
+			return 1
+		}
+		return -1
+	case xErr == nil && yErr == nil:
+		if xn < yn {
+			return -1
+		}
+		if xn > yn {
+			return 1
+		}
+		return 0
+	case xErr == nil:
+		return 1 // numbers sort after pre-release tags
+	case yErr == nil:
+		return -1
+	default:
+		return strings.Compare(strings.ToLower(x), strings.ToLower(y))
+	}
+}
@@ -0,0 +1,99 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxFuzz is how far, in lines, a hunk may have drifted from the position
+// recorded in its header and still be applied.
+const maxFuzz = 100
+
+// Apply applies the hunks of f to content and returns the result. Hunks must
+// match their context exactly, though they may have moved by a few lines;
+// otherwise Apply fails without partial results.
+func Apply(content string, f FileDiff) (string, error) {
+	if f.OldPath == "" && content != "" {
+		return "", fmt.Errorf("%s: file already exists", f.NewPath)
+	}
+
+	lines := splitLines(content)
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	offset := 0
+	var out []string
+	pos := 0 // next unconsumed line of the input
+
+	for i, h := range f.Hunks {
+		var old, repl []string
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Context:
+				old = append(old, l.Text)
+				repl = append(repl, l.Text)
+			case Removed:
+				old = append(old, l.Text)
+			case Added:
+				repl = append(repl, l.Text)
+			}
+		}
+
+		want := h.OldStart - 1 + offset
+		if h.OldLines == 0 {
+			want = h.OldStart + offset // insertion after line OldStart
+		}
+		at := findLines(lines, old, want, pos)
+		if at < 0 {
+			return "", fmt.Errorf("%s: hunk %d (@@ -%d,%d +%d,%d @@) does not apply", f.Path(), i+1, h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		}
+
+		out = append(out, lines[pos:at]...)
+		out = append(out, repl...)
+		pos = at + len(old)
+		offset = at - (h.OldStart - 1)
+		if h.OldLines == 0 {
+			offset = at - h.OldStart
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	if len(out) == 0 {
+		return "", nil
+	}
+	result := strings.Join(out, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findLines looks for old in lines, starting at want and moving outwards, but
+// never before min. It returns the index of the match or -1.
+func findLines(lines, old []string, want, min int) int {
+	matches := func(at int) bool {
+		if at < min || at+len(old) > len(lines) {
+			return false
+		}
+		for i, l := range old {
+			if lines[at+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; d <= maxFuzz; d++ {
+		if matches(want - d) {
+			return want - d
+		}
+		if d > 0 && matches(want+d) {
+			return want + d
+		}
+	}
+	return -1
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
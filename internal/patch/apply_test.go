@@ -0,0 +1,143 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		diff    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "replace line",
+			content: "a\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "several hunks",
+			content: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n 1\n+1.5\n 2\n@@ -9,2 +10,1 @@\n 9\n-10\n",
+			want:    "1\n1.5\n2\n3\n4\n5\n6\n7\n8\n9\n",
+		},
+		{
+			name:    "hunk moved down by fuzz",
+			content: "x\ny\nz\na\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "x\ny\nz\na\nB\nc\n",
+		},
+		{
+			name:    "hunk moved up by fuzz",
+			content: "a\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -5,3 +5,3 @@\n a\n-b\n+B\n c\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "second hunk keeps the first hunk's offset",
+			content: "new\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,1 +1,1 @@\n-1\n+one\n@@ -9,1 +9,1 @@\n-9\n+nine\n",
+			want:    "new\none\n2\n3\n4\n5\n6\n7\n8\nnine\n",
+		},
+		{
+			name:    "insertion after line",
+			content: "a\nb\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,0 +2,1 @@\n+inserted\n",
+			want:    "a\ninserted\nb\n",
+		},
+		{
+			name:    "no trailing newline is kept",
+			content: "a\nb",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			want:    "a\nc",
+		},
+		{
+			name:    "new file",
+			content: "",
+			diff:    NewFile("f", "one\ntwo\n"),
+			want:    "one\ntwo\n",
+		},
+		{
+			name:    "new file already exists",
+			content: "there\n",
+			diff:    NewFile("f", "one\n"),
+			wantErr: "already exists",
+		},
+		{
+			name:    "deleted file",
+			content: "a\nb\n",
+			diff:    "--- a/f\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+			want:    "",
+		},
+		{
+			name:    "context mismatch",
+			content: "a\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-x\n+B\n c\n",
+			wantErr: "hunk 1 (@@ -1,3 +1,3 @@) does not apply",
+		},
+		{
+			name:    "moved beyond fuzz",
+			content: strings.Repeat("pad\n", maxFuzz+5) + "a\nb\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n",
+			wantErr: "does not apply",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Parse(tt.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("Parse() returned %d files, want 1", len(files))
+			}
+			got, err := Apply(tt.content, files[0])
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Apply() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceLinesRoundTrip(t *testing.T) {
+	content := "1\n2\n3\n4\n5\n6\n7\n8\n"
+	tests := []struct {
+		name        string
+		start, end  int
+		replacement string
+		want        string
+	}{
+		{"replace one line", 4, 4, "four", "1\n2\n3\nfour\n5\n6\n7\n8\n"},
+		{"replace with more lines", 1, 2, "a\nb\nc", "a\nb\nc\n3\n4\n5\n6\n7\n8\n"},
+		{"delete lines", 7, 8, "", "1\n2\n3\n4\n5\n6\n"},
+		{"insert before line", 3, 2, "x", "1\n2\nx\n3\n4\n5\n6\n7\n8\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Parse(ReplaceLines("f", content, tt.start, tt.end, tt.replacement))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Apply(content, files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply(ReplaceLines()) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
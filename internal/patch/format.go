@@ -0,0 +1,67 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines kept around generated hunks.
+const contextLines = 3
+
+// ReplaceLines returns a patch that replaces lines start through end
+// (1-based, inclusive) of path with replacement. An end before start inserts
+// replacement before line start without removing anything.
+func ReplaceLines(path, content string, start, end int, replacement string) string {
+	lines := splitLines(content)
+	if start < 1 {
+		start = 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	from := max(start-contextLines, 1)
+	to := min(max(end, start-1)+contextLines, len(lines))
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for n := from; n < start; n++ {
+		fmt.Fprintf(&body, " %s\n", lines[n-1])
+		oldCount++
+		newCount++
+	}
+	for n := start; n <= end; n++ {
+		fmt.Fprintf(&body, "-%s\n", lines[n-1])
+		oldCount++
+	}
+	for _, l := range splitLines(replacement) {
+		fmt.Fprintf(&body, "+%s\n", l)
+		newCount++
+	}
+	for n := max(end+1, start); n <= to; n++ {
+		fmt.Fprintf(&body, " %s\n", lines[n-1])
+		oldCount++
+		newCount++
+	}
+
+	oldStart, newStart := from, from
+	if oldCount == 0 {
+		oldStart = from - 1
+	}
+	if newCount == 0 {
+		newStart = from - 1
+	}
+	return fmt.Sprintf("diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -%d,%d +%d,%d @@\n%s",
+		path, path, path, path, oldStart, oldCount, newStart, newCount, body.String())
+}
+
+// NewFile returns a patch that creates path with content.
+func NewFile(path, content string) string {
+	lines := splitLines(content)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "diff --git a/%s b/%s\nnew file mode 100644\n--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n", path, path, path, len(lines))
+	for _, l := range lines {
+		fmt.Fprintf(&sb, "+%s\n", l)
+	}
+	return sb.String()
+}
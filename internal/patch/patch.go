@@ -0,0 +1,203 @@
+package patch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LineKind identifies the role of a line within a hunk.
+type LineKind byte
+
+const (
+	Context LineKind = ' '
+	Added   LineKind = '+'
+	Removed LineKind = '-'
+)
+
+// Line is a single line of a hunk, without its leading marker or newline.
+type Line struct {
+	Kind LineKind
+	Text string
+}
+
+// Hunk is a contiguous region of change within a file.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// FileDiff holds the hunks for one file. OldPath is empty for added files and
+// NewPath is empty for deleted files.
+type FileDiff struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path returns the most relevant path for the file: the new path unless the
+// file was deleted.
+func (f FileDiff) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// AddedLines returns the new-side line numbers that were added or modified.
+func (f FileDiff) AddedLines() []int {
+	var lines []int
+	for _, h := range f.Hunks {
+		n := h.NewStart
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Added:
+				lines = append(lines, n)
+				n++
+			case Context:
+				n++
+			}
+		}
+	}
+	return lines
+}
+
+// Parse reads a unified diff, as produced by git or the context collector, into
+// per-file hunks.
+func Parse(diff string) ([]FileDiff, error) {
+	var files []FileDiff
+	var cur *FileDiff
+	var hunk *Hunk
+	// oldLeft and newLeft count the lines still expected in the current hunk,
+	// so that removed lines such as "--- x" are not mistaken for file headers.
+	var oldLeft, newLeft int
+
+	flushHunk := func() {
+		if cur != nil && hunk != nil {
+			cur.Hunks = append(cur.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+	flushFile := func() {
+		flushHunk()
+		if cur != nil {
+			files = append(files, *cur)
+		}
+		cur = nil
+	}
+
+	lines := strings.Split(diff, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				// Some tools strip the trailing space from empty context lines.
+				line = " "
+			}
+			kind := LineKind(line[0])
+			switch kind {
+			case Added:
+				newLeft--
+			case Removed:
+				oldLeft--
+			case Context:
+				oldLeft--
+				newLeft--
+			default:
+				continue // "\ No newline at end of file"
+			}
+			hunk.Lines = append(hunk.Lines, Line{Kind: kind, Text: line[1:]})
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushFile()
+			cur = &FileDiff{}
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if cur == nil || hunk != nil {
+				flushFile()
+				cur = &FileDiff{}
+			}
+			cur.OldPath = stripPrefix(strings.TrimPrefix(line, "--- "))
+			cur.NewPath = stripPrefix(strings.TrimPrefix(lines[i+1], "+++ "))
+			i++
+		case strings.HasPrefix(line, "@@ "):
+			if cur == nil {
+				return nil, fmt.Errorf("hunk header without file header at line %d", i+1)
+			}
+			flushHunk()
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			hunk = &h
+			oldLeft, newLeft = h.OldLines, h.NewLines
+		}
+	}
+	flushFile()
+	return files, nil
+}
+
+// ChangedLines maps each file in the diff to its added or modified line numbers.
+func ChangedLines(diff string) (map[string][]int, error) {
+	files, err := Parse(diff)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string][]int)
+	for _, f := range files {
+		if f.NewPath == "" {
+			continue
+		}
+		changed[f.NewPath] = append(changed[f.NewPath], f.AddedLines()...)
+	}
+	return changed, nil
+}
+
+func stripPrefix(path string) string {
+	// Drop a trailing timestamp as emitted by diff -u.
+	if i := strings.IndexByte(path, '\t'); i >= 0 {
+		path = path[:i]
+	}
+	if path == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
+
+// parseHunkHeader parses "@@ -l,s +l,s @@ optional section".
+func parseHunkHeader(line string) (Hunk, error) {
+	var h Hunk
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return h, fmt.Errorf("malformed hunk header %q", line)
+	}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(fields[1][1:]); err != nil {
+		return h, err
+	}
+	if h.NewStart, h.NewLines, err = parseRange(fields[2][1:]); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+func parseRange(s string) (start, count int, err error) {
+	count = 1
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		if count, err = strconv.Atoi(s[i+1:]); err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk range %q", s)
+		}
+		s = s[:i]
+	}
+	if start, err = strconv.Atoi(s); err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk range %q", s)
+	}
+	return start, count, nil
+}
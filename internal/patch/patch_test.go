@@ -0,0 +1,136 @@
+package patch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []FileDiff
+	}{
+		{
+			name: "modified file",
+			diff: `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+
+`,
+			want: []FileDiff{{
+				OldPath: "main.go",
+				NewPath: "main.go",
+				Hunks: []Hunk{{
+					OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3,
+					Lines: []Line{{Context, "package main"}, {Removed, "var x = 1"}, {Added, "var x = 2"}, {Context, ""}},
+				}},
+			}},
+		},
+		{
+			name: "new and deleted files",
+			diff: `diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+			want: []FileDiff{
+				{NewPath: "new.txt", Hunks: []Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []Line{{Added, "hello"}}}}},
+				{OldPath: "old.txt", Hunks: []Hunk{{OldStart: 1, OldLines: 2, NewStart: 0, NewLines: 0, Lines: []Line{{Removed, "a"}, {Removed, "b"}}}}},
+			},
+		},
+		{
+			name: "removed lines that look like headers",
+			diff: `--- a/notes.md
++++ b/notes.md
+@@ -1,2 +1,1 @@
+--- a/x
+-++ b/x
++text
+`,
+			want: []FileDiff{{
+				OldPath: "notes.md",
+				NewPath: "notes.md",
+				Hunks: []Hunk{{
+					OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 1,
+					Lines: []Line{{Removed, "-- a/x"}, {Removed, "++ b/x"}, {Added, "text"}},
+				}},
+			}},
+		},
+		{
+			name: "diff -u timestamps and no newline marker",
+			diff: "--- a.txt\t2024-01-01 00:00:00\n+++ a.txt\t2024-01-02 00:00:00\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n",
+			want: []FileDiff{{
+				OldPath: "a.txt",
+				NewPath: "a.txt",
+				Hunks:   []Hunk{{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []Line{{Removed, "a"}, {Added, "b"}}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+	}{
+		{"hunk without file", "@@ -1 +1 @@\n-a\n+b\n"},
+		{"malformed header", "--- a/x\n+++ b/x\n@@ -a +1 @@\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.diff); err == nil {
+				t.Error("Parse() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	diff := `--- a/f.go
++++ b/f.go
+@@ -1,4 +1,5 @@
+ a
+-b
++B
++C
+ c
+ d
+@@ -10,2 +11,3 @@
+ x
++y
+ z
+`
+	got, err := ChangedLines(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]int{"f.go": {2, 3, 12}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedLines() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,227 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// Target is where patched files are read from and written to.
+type Target interface {
+	// Read returns the current content of path and whether it exists.
+	Read(path string) (string, bool, error)
+	// Write replaces the content of path, creating it if needed.
+	Write(path, content string) error
+	// Remove deletes path.
+	Remove(path string) error
+}
+
+// WorktreeTarget applies patches to files in the working tree under Root.
+type WorktreeTarget struct {
+	Root string
+}
+
+func (t WorktreeTarget) Read(path string) (string, bool, error) {
+	data, err := os.ReadFile(filepath.Join(t.Root, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	return string(data), err == nil, err
+}
+
+func (t WorktreeTarget) Write(path, content string) error {
+	full := filepath.Join(t.Root, path)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(full); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(full, []byte(content), mode)
+}
+
+func (t WorktreeTarget) Remove(path string) error {
+	return os.Remove(filepath.Join(t.Root, path))
+}
+
+// IndexTarget applies patches to the git index only, leaving the working
+// tree untouched.
+type IndexTarget struct {
+	Repo *git.Repository
+}
+
+func (t IndexTarget) Read(path string) (string, bool, error) {
+	idx, err := t.Repo.Storer.Index()
+	if err != nil {
+		return "", false, err
+	}
+	entry, err := idx.Entry(path)
+	if err == index.ErrEntryNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	blob, err := t.Repo.BlobObject(entry.Hash)
+	if err != nil {
+		return "", false, err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return "", false, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return string(data), err == nil, err
+}
+
+func (t IndexTarget) Write(path, content string) error {
+	obj := t.Repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, bytes.NewBufferString(content)); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	hash, err := t.Repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+
+	idx, err := t.Repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	entry, err := idx.Entry(path)
+	if err == index.ErrEntryNotFound {
+		entry = idx.Add(path)
+		entry.Mode = filemode.Regular
+	} else if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	entry.Size = uint32(len(content))
+	entry.ModifiedAt = time.Now()
+	return t.Repo.Storer.SetIndex(idx)
+}
+
+func (t IndexTarget) Remove(path string) error {
+	idx, err := t.Repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	if _, err := idx.Remove(path); err != nil {
+		return err
+	}
+	return t.Repo.Storer.SetIndex(idx)
+}
+
+// CheckPath rejects paths a patch must not touch: absolute paths, paths with
+// a ".." element, which could leave the repository, and paths inside .git.
+func CheckPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	slashed := filepath.ToSlash(path)
+	if filepath.IsAbs(path) || strings.HasPrefix(slashed, "/") || filepath.VolumeName(path) != "" {
+		return fmt.Errorf("%s: absolute path", path)
+	}
+	for _, elem := range strings.Split(slashed, "/") {
+		switch {
+		case elem == "..":
+			return fmt.Errorf("%s: path leaves the repository", path)
+		case strings.EqualFold(elem, ".git"):
+			return fmt.Errorf("%s: path is inside .git", path)
+		}
+	}
+	return nil
+}
+
+// ApplyTo applies a multi-file patch to target as a unit: every file is
+// patched in memory first, and if writing any of them fails the files already
+// written are restored. It returns the paths that were changed. Patches to
+// paths rejected by CheckPath are not applied at all.
+func ApplyTo(target Target, diff string) ([]string, error) {
+	files, err := Parse(diff)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("patch contains no file changes")
+	}
+	for _, f := range files {
+		for _, path := range []string{f.OldPath, f.NewPath} {
+			if err := CheckPath(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	type change struct {
+		path     string
+		old, new string
+		existed  bool
+		remove   bool
+	}
+	var changes []change
+	for _, f := range files {
+		path := f.Path()
+		old, exists, err := target.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		if !exists && f.OldPath != "" {
+			return nil, fmt.Errorf("%s: file does not exist", path)
+		}
+		patched, err := Apply(old, f)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change{path: path, old: old, new: patched, existed: exists, remove: f.NewPath == ""})
+	}
+
+	var applied []change
+	rollback := func() {
+		for i := len(applied) - 1; i >= 0; i-- {
+			c := applied[i]
+			if c.existed {
+				_ = target.Write(c.path, c.old)
+			} else {
+				_ = target.Remove(c.path)
+			}
+		}
+	}
+	var paths []string
+	for _, c := range changes {
+		var err error
+		if c.remove {
+			err = target.Remove(c.path)
+		} else {
+			err = target.Write(c.path, c.new)
+		}
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("%s: %w", c.path, err)
+		}
+		applied = append(applied, c)
+		paths = append(paths, c.path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,237 @@
+package patch
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// memTarget is an in-memory Target whose writes to failPath fail.
+type memTarget struct {
+	files    map[string]string
+	failPath string
+}
+
+func (t *memTarget) Read(path string) (string, bool, error) {
+	content, ok := t.files[path]
+	return content, ok, nil
+}
+
+func (t *memTarget) Write(path, content string) error {
+	if path == t.failPath {
+		return errors.New("disk full")
+	}
+	t.files[path] = content
+	return nil
+}
+
+func (t *memTarget) Remove(path string) error {
+	if _, ok := t.files[path]; !ok {
+		return os.ErrNotExist
+	}
+	delete(t.files, path)
+	return nil
+}
+
+const twoFilePatch = `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-a
++A
+diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-b
++B
+`
+
+func TestApplyTo(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		failPath  string
+		diff      string
+		want      map[string]string
+		wantPaths []string
+		wantErr   string
+	}{
+		{
+			name:      "all files",
+			files:     map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			diff:      twoFilePatch,
+			want:      map[string]string{"a.txt": "A\n", "b.txt": "B\n"},
+			wantPaths: []string{"a.txt", "b.txt"},
+		},
+		{
+			name:      "new and deleted files",
+			files:     map[string]string{"old.txt": "x\n"},
+			diff:      NewFile("new.txt", "y\n") + "diff --git a/old.txt b/old.txt\n--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n",
+			want:      map[string]string{"new.txt": "y\n"},
+			wantPaths: []string{"new.txt", "old.txt"},
+		},
+		{
+			name:    "failed hunk writes nothing",
+			files:   map[string]string{"a.txt": "a\n", "b.txt": "changed\n"},
+			diff:    twoFilePatch,
+			want:    map[string]string{"a.txt": "a\n", "b.txt": "changed\n"},
+			wantErr: "does not apply",
+		},
+		{
+			name:    "missing file",
+			files:   map[string]string{"a.txt": "a\n"},
+			diff:    twoFilePatch,
+			want:    map[string]string{"a.txt": "a\n"},
+			wantErr: "b.txt: file does not exist",
+		},
+		{
+			name:     "failed write rolls back",
+			files:    map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			failPath: "b.txt",
+			diff:     twoFilePatch,
+			want:     map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			wantErr:  "b.txt: disk full",
+		},
+		{
+			name:     "rollback removes created files",
+			files:    map[string]string{"a.txt": "a\n"},
+			failPath: "a.txt",
+			diff:     NewFile("new.txt", "y\n") + twoFilePatch[:strings.Index(twoFilePatch, "diff --git a/b.txt")],
+			want:     map[string]string{"a.txt": "a\n"},
+			wantErr:  "a.txt: disk full",
+		},
+		{
+			name:    "path outside the repo",
+			files:   map[string]string{},
+			diff:    NewFile("../evil.sh", "x\n"),
+			want:    map[string]string{},
+			wantErr: "leaves the repository",
+		},
+		{
+			name:    "absolute path",
+			files:   map[string]string{},
+			diff:    "--- /dev/null\n+++ /etc/evil\n@@ -0,0 +1 @@\n+x\n",
+			want:    map[string]string{},
+			wantErr: "absolute path",
+		},
+		{
+			name:    "path inside .git",
+			files:   map[string]string{},
+			diff:    NewFile(".git/hooks/pre-commit", "x\n"),
+			want:    map[string]string{},
+			wantErr: "inside .git",
+		},
+		{
+			name:    "empty patch",
+			files:   map[string]string{},
+			diff:    "",
+			want:    map[string]string{},
+			wantErr: "no file changes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &memTarget{files: maps.Clone(tt.files), failPath: tt.failPath}
+			paths, err := ApplyTo(target, tt.diff)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ApplyTo() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(target.files, tt.want) {
+				t.Errorf("files = %v, want %v", target.files, tt.want)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.wantPaths, ",") {
+				t.Errorf("paths = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"main.go", true},
+		{"internal/patch/patch.go", true},
+		{"..hidden/file", true},
+		{".github/workflows/ci.yml", true},
+		{"", true},
+		{"../x", false},
+		{"a/../../x", false},
+		{"a/..", false},
+		{"/etc/passwd", false},
+		{".git/config", false},
+		{"sub/.git/config", false},
+		{".GIT/hooks/pre-commit", false},
+	}
+	for _, tt := range tests {
+		if err := CheckPath(tt.path); (err == nil) != tt.ok {
+			t.Errorf("CheckPath(%q) = %v, want ok %v", tt.path, err, tt.ok)
+		}
+	}
+}
+
+func TestWorktreeTarget(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	target := WorktreeTarget{Root: root}
+	if _, err := ApplyTo(target, NewFile("dir/new.txt", "new\n")+twoFilePatch[:strings.Index(twoFilePatch, "diff --git a/b.txt")]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(root, "dir", "new.txt"))
+	if err != nil || string(got) != "new\n" {
+		t.Errorf("dir/new.txt = %q, %v, want %q", got, err, "new\n")
+	}
+	info, err := os.Stat(filepath.Join(root, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("a.txt mode = %v, want the original 0600", info.Mode().Perm())
+	}
+}
+
+func TestIndexTarget(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := IndexTarget{Repo: repo}
+	if err := target.Write("a.txt", "a\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ApplyTo(target, twoFilePatch); err == nil {
+		t.Fatal("ApplyTo() succeeded with b.txt missing from the index")
+	}
+	if content, _, _ := target.Read("a.txt"); content != "a\n" {
+		t.Errorf("a.txt = %q after failed patch, want it unchanged", content)
+	}
+
+	if _, err := ApplyTo(target, twoFilePatch[:strings.Index(twoFilePatch, "diff --git a/b.txt")]); err != nil {
+		t.Fatal(err)
+	}
+	if content, ok, err := target.Read("a.txt"); err != nil || !ok || content != "A\n" {
+		t.Errorf("a.txt = %q, %v, %v, want %q", content, ok, err, "A\n")
+	}
+	if err := target.Remove("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := target.Read("a.txt"); ok {
+		t.Error("a.txt still in the index after Remove")
+	}
+}
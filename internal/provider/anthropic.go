@@ -18,7 +18,7 @@ func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
 		option.WithAPIKey(apiKey),
 	)
 	return &AnthropicProvider{
-		client: client,
+		client: &client,
 		model:  model,
 	}
 }
@@ -32,8 +32,8 @@ func (p *AnthropicProvider) Complete(prompt, systemPrompt string, useJSON bool)
 	}
 
 	req := anthropic.MessageNewParams{
-		Model:     anthropic.Model(p.model),
-		System:    systemMessages,
+		Model:  anthropic.Model(p.model),
+		System: systemMessages,
 		Messages: []anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
 		},
@@ -51,4 +51,3 @@ func (p *AnthropicProvider) Complete(prompt, systemPrompt string, useJSON bool)
 
 	return resp.Content[0].Text, nil
 }
-
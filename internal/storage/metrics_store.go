@@ -81,3 +81,76 @@ func (s *MetricsStore) GetMetrics(period time.Duration) ([]Metric, error) {
 	}
 	return results, nil
 }
+
+// FunctionMetric is a complexity sample for one function at one point in time.
+type FunctionMetric struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Revision   string    `json:"revision,omitempty"`
+	File       string    `json:"file"`
+	Function   string    `json:"function"`
+	Cyclomatic int       `json:"cyclomatic"`
+	Cognitive  int       `json:"cognitive"`
+	Length     int       `json:"length"`
+	Nesting    int       `json:"nesting"`
+	Params     int       `json:"params"`
+}
+
+func (s *MetricsStore) complexityDir() string {
+	return filepath.Join(s.metricsDir, "complexity")
+}
+
+// RecordComplexity appends function complexity samples to the day's history.
+func (s *MetricsStore) RecordComplexity(samples []FunctionMetric) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	dir := s.complexityDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	filePath := filepath.Join(dir, samples[0].Timestamp.Format("2006-01-02")+".json")
+
+	var history []FunctionMetric
+	if data, err := os.ReadFile(filePath); err == nil {
+		_ = json.Unmarshal(data, &history)
+	}
+	history = append(history, samples...)
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// GetComplexityHistory returns the samples recorded within period, oldest
+// first. Empty file or function arguments match everything.
+func (s *MetricsStore) GetComplexityHistory(period time.Duration, file, function string) ([]FunctionMetric, error) {
+	var results []FunctionMetric
+	startTime := time.Now().Add(-period)
+
+	files, err := os.ReadDir(s.complexityDir())
+	if err != nil {
+		return nil, nil // Return empty if dir doesn't exist
+	}
+	for _, f := range files {
+		fileDate, err := time.Parse("2006-01-02", strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil || fileDate.Before(startTime.Add(-24*time.Hour)) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.complexityDir(), f.Name()))
+		if err != nil {
+			continue
+		}
+		var samples []FunctionMetric
+		if json.Unmarshal(data, &samples) != nil {
+			continue
+		}
+		for _, m := range samples {
+			if m.Timestamp.Before(startTime) || (file != "" && m.File != file) || (function != "" && m.Function != function) {
+				continue
+			}
+			results = append(results, m)
+		}
+	}
+	return results, nil
+}